@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/bmflynn/cmrfetch/internal/log"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	flags := listCmd.Flags()
+	flags.BoolP("verbose", "v", false, "Verbose output")
+	flags.StringP("output", "o", "short", "Output format. One of short or json.")
+
+	flags = purgeCmd.Flags()
+	flags.BoolP("verbose", "v", false, "Verbose output")
+	flags.Duration("older-than", 0, "Only purge entries older than this duration, e.g., 24h.")
+	flags.String("match", "", "Only purge entries with a request url containing this string.")
+
+	Cmd.AddCommand(listCmd)
+	Cmd.AddCommand(purgeCmd)
+}
+
+func failOnError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func elipsis(s string, maxLen int) string {
+	if len(s) < maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+var Cmd = &cobra.Command{
+	Use:   "cache",
+	Short: "List or purge cached CMR responses",
+	Long: `
+List or purge cached CMR responses
+
+The collections, granules, keywords, and providers commands cache CMR responses
+in the user cache directory so repeated searches do not have to query CMR again.
+Responses are used for --cache-ttl, 1h by default or 30d for provider holdings,
+and expired responses are removed automatically. Use --refresh with those
+commands to ignore cached responses or --no-cache to disable the cache. Search
+results are not cached when downloading granules.
+`,
+	Example: `
+  List cached responses:
+
+    cmrfetch cache list
+
+  Remove cached responses older than a day:
+
+    cmrfetch cache purge --older-than 24h
+`,
+}
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Args:    cobra.NoArgs,
+	Short:   "List cached CMR responses",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		verbose, err := flags.GetBool("verbose")
+		failOnError(err)
+		output, err := flags.GetString("output")
+		failOnError(err)
+
+		log.SetVerbose(verbose)
+
+		if output != "short" && output != "json" {
+			return fmt.Errorf("--output must be one of short, json")
+		}
+
+		cache, err := newCache()
+		if err != nil {
+			return err
+		}
+		log.Debug("cache dir: %s", cache.Dir())
+		entries, err := cache.Entries()
+		if err != nil {
+			log.Fatalf("failed! %s", err)
+		}
+
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			for _, entry := range entries {
+				if err := enc.Encode(entry); err != nil {
+					log.Fatalf("failed! %s", err)
+				}
+			}
+			return nil
+		}
+
+		var total int64
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.SetStyle(table.StyleLight)
+		t.AppendHeader(table.Row{"created", "age", "expires", "size", "hits", "url"})
+		for _, entry := range entries {
+			total += entry.Size
+			url := entry.URL
+			if entry.SearchAfter != "" {
+				url += " (search-after " + entry.SearchAfter + ")"
+			}
+			t.AppendRow(table.Row{
+				entry.Created.Format(time.RFC3339), time.Since(entry.Created).Round(time.Second),
+				entry.Expires.Format(time.RFC3339), internal.ByteCountSI(entry.Size), entry.Header.Get("cmr-hits"), elipsis(url, 96),
+			})
+		}
+		t.AppendFooter(table.Row{len(entries), "", "", internal.ByteCountSI(total), "", ""})
+		t.Render()
+		return nil
+	},
+}
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Args:  cobra.NoArgs,
+	Short: "Remove cached CMR responses",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		verbose, err := flags.GetBool("verbose")
+		failOnError(err)
+		olderThan, err := flags.GetDuration("older-than")
+		failOnError(err)
+		match, err := flags.GetString("match")
+		failOnError(err)
+
+		log.SetVerbose(verbose)
+
+		cache, err := newCache()
+		if err != nil {
+			return err
+		}
+		var remove func(internal.ResponseCacheEntry) bool
+		if olderThan > 0 || match != "" {
+			remove = func(entry internal.ResponseCacheEntry) bool {
+				return time.Since(entry.Created) >= olderThan && strings.Contains(entry.URL, match)
+			}
+		}
+		count, err := cache.Purge(remove)
+		if err != nil {
+			log.Fatalf("failed! %s", err)
+		}
+		log.Printf("removed %d cached responses", count)
+		return nil
+	},
+}
+
+func newCache() (*internal.ResponseCache, error) {
+	dir, err := internal.DefaultResponseCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("resolving cache dir: %w", err)
+	}
+	return internal.NewResponseCache(dir, 0), nil
+}
@@ -12,6 +12,12 @@ import (
 	"github.com/spf13/pflag"
 )
 
+// Collection time filters are only applied when provided, so they have no default
+var (
+	timerange internal.TimeRangeValue
+	since     internal.TimeValue
+)
+
 var requiredFlagNames = []string{
 	"keyword",
 	"provider",
@@ -19,6 +25,14 @@ var requiredFlagNames = []string{
 	"instrument",
 	"platform",
 	"title",
+	"bounding-box",
+	"polygon",
+	"point",
+	"circle",
+	"region",
+	"attribute",
+	"timerange",
+	"since",
 }
 
 func init() {
@@ -31,8 +45,8 @@ func init() {
 		"Filter on provider name. May be provided more than once or comma separated. "+
 			"Example providers include ASIPS or LAADS. For a listing of available providers "+
 			"see https://cmr.earthdata.nasa.gov/search/site/collections/directory")
-	flags.String("since", "", "Filter to collections that have a revision date greater"+
-		"or equal to this UTC time, formatted as <yyyy>-<mm>-<dd>T<hh>:<mm>:<dd>Z")
+	flags.Var(&since, "since", "Filter to collections that have a revision date greater "+
+		"or equal to this UTC time, as "+internal.TimeExprHelp)
 	flags.StringSliceP("shortname", "s", nil, "Filter on collection short name or pattern (support ? or *)")
 	flags.StringSliceP("instrument", "i", []string{},
 		"Filter on instrument short name. May be provided more than once or comma separated. "+
@@ -51,6 +65,24 @@ func init() {
 	flags.Bool("has-granules", true,
 		"Filter to collections with granules.")
 	flags.StringP("datatype", "d", "", "Collection data type, e.g., NRT, SCIENCE_QUALITY, OTHER, etc...")
+	flags.Var(&timerange, "timerange",
+		"Collections with a temporal extent overlapping the timerange, as <start>,[<end>], where times "+
+			"are "+internal.TimeExprHelp)
+	flags.Float64Slice("bounding-box", nil, "Collections overlapping a bounding box, where the corner "+
+		"points are provided lon1,lat1,lon2,lat2.")
+	flags.Float64Slice("polygon", nil,
+		"Collections overlapping a polygon. Polygon points are provided in counter-clockwise order. The "+
+			"last point should match the first point to close the polygon. The values are listed comma "+
+			"separated in longitude latitude order, i.e. lon1,lat1,lon2,lat2,lon3,lat3, and so on.")
+	flags.Float64Slice("point", nil, "Collections containing point lon,lat.")
+	flags.Float64Slice("circle", nil, "Collections overlapping a circle, where the circle is defined as "+
+		"centerlon,centerlat,radius.")
+	internal.AddRegionFlag(flags)
+	internal.AddAttributeFlag(flags)
+	internal.AddCacheFlags(flags, internal.DefaultResponseCacheTTL)
+	flags.Int("limit", 0, "Stop after this many collections. The default, 0, is no limit.")
+	flags.Bool("count", false,
+		"Only print the number of matching collections, without retrieving them.")
 }
 
 func failOnError(err error) {
@@ -87,6 +119,18 @@ var Cmd = &cobra.Command{
   Search for a collection by platform:
 
     cmrfetch collections -p Suomi-NPP
+
+  Search for VIIRS collections covering a bounding box:
+
+    cmrfetch collections -i VIIRS --bounding-box=-105,35,-95,45
+
+  Search for MODIS collections with data during the 2012 derecho:
+
+    cmrfetch collections -i MODIS --timerange 2012-06-29,2012-06-30
+
+  Search for collections with a sinusoidal tile attribute:
+
+    cmrfetch collections -s "MOD09*" --attribute int,HORIZONTALTILENUMBER,8
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		flags := cmd.Flags()
@@ -103,7 +147,16 @@ var Cmd = &cobra.Command{
 		failOnError(err)
 
 		log.SetVerbose(verbose)
-		api := internal.NewCMRSearchAPI()
+
+		env, err := internal.CMREnvFromFlags(flags)
+		if err != nil {
+			return err
+		}
+		cache, err := internal.ResponseCacheFromFlags(flags)
+		if err != nil {
+			return err
+		}
+		api := internal.NewCMRSearchAPI().WithURL(env.SearchURL()).WithCache(cache)
 
 		var writer outputWriter
 		switch output {
@@ -121,7 +174,24 @@ var Cmd = &cobra.Command{
 			return fmt.Errorf("at least one of %s is required", requiredFlags())
 		}
 
-		return do(api, params, writer)
+		count, err := flags.GetBool("count")
+		failOnError(err)
+		if count {
+			return doCount(api, params)
+		}
+
+		attrs, err := internal.AttributesFromFlags(flags)
+		failOnError(err) // already validated by newParams
+		extra := []string{}
+		seen := map[string]bool{}
+		for _, attr := range attrs {
+			if name := internal.AttributeFieldPrefix + attr.Name; !seen[name] {
+				extra = append(extra, name)
+				seen[name] = true
+			}
+		}
+
+		return do(api, params, writer, extra)
 	},
 }
 
@@ -152,6 +222,13 @@ func newParams(flags *pflag.FlagSet) (*internal.SearchCollectionParams, error) {
 	}
 	params.SortBy(s)
 
+	n, err := flags.GetInt("limit")
+	failOnError(err)
+	if n < 0 {
+		return params, fmt.Errorf("--limit must not be negative")
+	}
+	params.Limit(n)
+
 	a, err := flags.GetStringSlice("provider")
 	failOnError(err)
 	params.Providers(a...)
@@ -192,14 +269,62 @@ func newParams(flags *pflag.FlagSet) (*internal.SearchCollectionParams, error) {
 		params.Standard(b)
 	}
 
+	if flags.Changed("since") {
+		params.UpdatedSince(*since.Time)
+	}
+
+	if flags.Changed("timerange") {
+		params.Timerange(internal.TimeRange{Start: *timerange.Start, End: timerange.End})
+	}
+
+	f, err := flags.GetFloat64Slice("bounding-box")
+	failOnError(err)
+	params.BoundingBox(f)
+
+	f, err = flags.GetFloat64Slice("polygon")
+	failOnError(err)
+	params.Polygon(f)
+
+	f, err = flags.GetFloat64Slice("point")
+	failOnError(err)
+	params.Point(f)
+
+	f, err = flags.GetFloat64Slice("circle")
+	failOnError(err)
+	params.Circle(f)
+
+	attrs, err := internal.AttributesFromFlags(flags)
+	if err != nil {
+		return params, err
+	}
+	params.Attributes(attrs...)
+
+	region, err := internal.RegionFromFlags(flags)
+	if err != nil {
+		return params, err
+	}
+	params.Region(region)
+
 	return params, nil
 }
 
-func do(api *internal.CMRSearchAPI, params *internal.SearchCollectionParams, writer outputWriter) error {
-	zult, err := api.SearchCollections(context.Background(), params)
+func do(api *internal.CMRSearchAPI, params *internal.SearchCollectionParams, writer outputWriter, extra []string) error {
+	// Stops the search if the writer fails before all results are received
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	zult, err := api.SearchCollections(ctx, params)
 	if err != nil {
 		return err
 	}
 
-	return writer(zult, os.Stdout)
+	return writer(zult, os.Stdout, extra)
+}
+
+func doCount(api *internal.CMRSearchAPI, params *internal.SearchCollectionParams) error {
+	hits, err := api.CountCollections(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hits)
+	return nil
 }
@@ -7,30 +7,39 @@ import (
 	"github.com/jedib0t/go-pretty/v6/table"
 )
 
-type outputWriter func(internal.CollectionResult, io.Writer) error
+// outputWriter writes collections including any extra fields, e.g., additional attributes
+type outputWriter func(zult internal.CollectionResult, w io.Writer, extra []string) error
 
-func tableWriter(zult internal.CollectionResult, w io.Writer) error {
+func tableWriter(zult internal.CollectionResult, w io.Writer, extra []string) error {
 	t := table.NewWriter()
 	t.SetOutputMirror(w)
 	t.SetStyle(table.StyleLight)
 
-	t.AppendHeader(table.Row{"shortname", "version", "concept", "revision_id", "provider"})
+	header := table.Row{"shortname", "version", "concept", "revision_id", "provider"}
+	for _, name := range extra {
+		header = append(header, name)
+	}
+	t.AppendHeader(header)
 
 	for col := range zult.Ch {
-		t.AppendRow(table.Row{
+		row := table.Row{
 			col["shortname"],
 			col["version"],
 			col["concept_id"],
 			col["revision_id"],
 			col["provider"],
-		})
+		}
+		for _, name := range extra {
+			row = append(row, col[name])
+		}
+		t.AppendRow(row)
 	}
 
 	t.Render()
 	return zult.Err()
 }
 
-func writeCollection(zult internal.CollectionResult, w io.Writer, long bool) error {
+func writeCollection(zult internal.CollectionResult, w io.Writer, extra []string, long bool) error {
 	fields := []string{
 		"shortname",
 		"version",
@@ -44,6 +53,7 @@ func writeCollection(zult internal.CollectionResult, w io.Writer, long bool) err
 		"temporal_extents",
 		"infourls",
 	}
+	fields = append(fields, extra...)
 
 	for col := range zult.Ch {
 		t := table.NewWriter()
@@ -63,10 +73,10 @@ func writeCollection(zult internal.CollectionResult, w io.Writer, long bool) err
 	return zult.Err()
 }
 
-func shortWriter(zult internal.CollectionResult, w io.Writer) error {
-	return writeCollection(zult, w, false)
+func shortWriter(zult internal.CollectionResult, w io.Writer, extra []string) error {
+	return writeCollection(zult, w, extra, false)
 }
 
-func longWriter(zult internal.CollectionResult, w io.Writer) error {
-	return writeCollection(zult, w, true)
+func longWriter(zult internal.CollectionResult, w io.Writer, extra []string) error {
+	return writeCollection(zult, w, extra, true)
 }
@@ -14,13 +14,14 @@ import (
 )
 
 var (
-	timerange   internal.TimeRangeValue = internal.NewTimeRangeValue()
-	validFields                         = []string{
+	timerange     internal.TimeRangesValue = internal.NewTimeRangesValue()
+	defaultFields                          = []string{
 		"name", "size", "checksum", "checksum_alg", "download_url", "native_id", "revision_id",
 		"concept_id", "collection", "download_direct_url", "daynight", "timerange", "boundingbox",
 		"provider_dates",
 	}
-	defaultFields = validFields
+	validFields = append(defaultFields, "size_bytes", "revision_date", "geometry", "cloud_cover",
+		"orbit_number", "equator_crossing_longitude", "equator_crossing_date", "attributes")
 )
 
 func failOnError(err error) {
@@ -51,6 +52,9 @@ NASA Earthdata Authentication
 
     machine urs.earthdata.nasa.gov login <username> password <plain text password>
 
+  When using --cmr-env uat or sit the corresponding Earthdata Login host, i.e.,
+  uat.urs.earthdata.nasa.gov or sit.urs.earthdata.nasa.gov, must be used instead.
+
   NOTE: It is very important that this file is only accessible via your user. On
   Linux and OSX this can be done via 'chmod 0600 ~/.netrc'.
 
@@ -71,61 +75,115 @@ NASA Earthdata Authentication
   Search for granules by filename:
 
     cmrfetch granules -c C1964798938-LAADS -f CLDMSK_L2_VIIRS_NOAA20.A2023115.0142.001.2023115140055.nc 
+
+  Search for granules from the last 6 hours, or for a range of days of year:
+
+    cmrfetch granules -s AERDT_L2_VIIRS_SNPP_NRT -t -6h,
+    cmrfetch granules -s AERDT_L2_VIIRS_SNPP -t 2023115,2023-118
+
+  Search for granules from every July over ten years:
+
+    cmrfetch granules -s AERDT_L2_VIIRS_SNPP -t 2013-01-01,2023-01-01 --season 07-01,07-31
+
+  Search for granules with at most 20% cloud cover:
+
+    cmrfetch granules -s MOD021KM -t 2023-06-01,2023-06-02 --cloud-cover ,20
+
+  Search for MODIS sinusoidal tile h08v05:
+
+    cmrfetch granules -s MOD09GA -t 2023-06-01,2023-06-02 \
+      --attribute int,HORIZONTALTILENUMBER,8 --attribute int,VERTICALTILENUMBER,5
+
+  Search for granules oldest first, e.g., for a backfill:
+
+    cmrfetch granules -s AERDT_L2_VIIRS_SNPP -t 2023-01-01,2023-02-01 --sortby start_date
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		flags := cmd.Flags()
 
-		if !flags.Changed("collection") &&
-			!flags.Changed("nativeid") &&
-			!flags.Changed("shortname") &&
-			!flags.Changed("filename") {
-			return fmt.Errorf("at least one of --collection, --shortname, --nativeid, or --filename is required")
+		if err := validateSearchFlags(flags); err != nil {
+			return err
 		}
 
-		token, err := flags.GetString("edltoken")
-		failOnError(err)
-		netrc, err := flags.GetBool("netrc")
-		failOnError(err)
 		verbose, err := flags.GetBool("verbose")
 		failOnError(err)
 		output, err := flags.GetString("output")
 		failOnError(err)
 
-		yes, err := flags.GetBool("yes")
-		failOnError(err)
-		destdir, err := flags.GetString("download")
-		failOnError(err)
-		concurrency, err := flags.GetInt("download-concurrency")
-		failOnError(err)
-		clobber, err := flags.GetBool("download-clobber")
-		failOnError(err)
-		downloadSkipChecksum, err := flags.GetBool("download-skip-checksum")
-		failOnError(err)
-
 		fields, err := flags.GetStringSlice("fields")
 		failOnError(err)
 		for _, name := range fields {
-			if !arrayContains(validFields, name) {
+			if !arrayContains(validFields, name) && !strings.HasPrefix(name, internal.AttributeFieldPrefix) {
 				return fmt.Errorf("%s is not a valid field name", name)
 			}
 		}
 
-		if flags.Changed("filename") && !flags.Changed("collection") {
-			return fmt.Errorf("--collection is required when using --filename")
-		}
-
 		params, err := newParams(flags)
 		if err != nil {
 			return err
 		}
+		if !flags.Changed("fields") {
+			fields = append(fields, attributeFields(flags)...)
+		}
+		sortby, err := flags.GetStringSlice("sortby")
+		failOnError(err)
+		for _, key := range sortby {
+			if !validSortField(key) {
+				return fmt.Errorf("invalid sort field %q; expected one of %s", key, strings.Join(sortByFields, ", "))
+			}
+		}
+		params.SortBy(sortby...)
+		params.Timeranges(timerange.Ranges...)
+		limit, err := flags.GetInt("limit")
+		failOnError(err)
+		if limit < 0 {
+			return fmt.Errorf("--limit must not be negative")
+		}
+		params.Limit(limit)
+		count, err := flags.GetBool("count")
+		failOnError(err)
+		destdir, err := flags.GetString("download")
+		failOnError(err)
+		if count && destdir != "" {
+			return fmt.Errorf("--count cannot be used with --download")
+		}
 
 		log.SetVerbose(verbose)
 
-		api := internal.NewCMRSearchAPI()
-
-		if destdir != "" {
-			err = doDownload(context.TODO(), api, params, destdir, token, netrc, clobber, yes, downloadSkipChecksum, concurrency)
-		} else {
+		env, err := internal.CMREnvFromFlags(flags)
+		if err != nil {
+			return err
+		}
+		// Downloads always use current search results
+		var cache *internal.ResponseCache
+		if destdir == "" {
+			cache, err = internal.ResponseCacheFromFlags(flags)
+			if err != nil {
+				return err
+			}
+		}
+		api := internal.NewCMRSearchAPI().WithURL(env.SearchURL()).WithCache(cache)
+
+		switch {
+		case count:
+			err = doCount(api, params)
+		case destdir != "":
+			var opts downloadOptions
+			opts, err = newDownloadOptions(flags)
+			if err != nil {
+				return err
+			}
+			opts.yes, err = flags.GetBool("yes")
+			failOnError(err)
+			// Stops the search if the download returns before all results are received
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var zult internal.GranuleResult
+			zult, err = searchForDownload(ctx, api, params, opts.yes)
+			if err == nil {
+				err = doDownload(ctx, api, env, zult, opts)
+			}
+		default:
 			err = do(api, params, output, fields)
 		}
 		if err != nil {
@@ -148,7 +206,36 @@ func init() {
 			fmt.Sprintf("exist it will be created. More than %v total granules in the ", maxResultsWithoutPrompt)+
 			"result set will require confirmation, which can be skipped using --yes. By default, "+
 			"If a file exists by name in the destination directory it will be skipped; see --download-clobber. "+
-			"Checksums are verified for all downloaded files, if a checksum is available.")
+			"Checksums are verified for all downloaded files, if a checksum is available. Cached search "+
+			"results are not used when downloading.")
+	addDownloadFlags(flags)
+	addSearchFlags(flags, &timerange)
+	flags.StringSlice("sortby", nil,
+		fmt.Sprintf("Sort by one or more of %s. Prefix the field name by - to sort descending, or + "+
+			"for ascending. The default is -start_date, i.e., newest first.", strings.Join(sortByFields, ", ")))
+	flags.Int("limit", 0,
+		"Stop after this many granules, which also applies to --download. The default, 0, is no limit.")
+	flags.Bool("count", false,
+		"Only print the number of matching granules, without retrieving them. Useful for planning "+
+			"large downloads.")
+	internal.AddCacheFlags(flags, internal.DefaultResponseCacheTTL)
+	flags.StringSlice("fields", defaultFields,
+		"Fields to include in output; ignored for --output=short. "+strings.Join(validFields, ", ")+
+			", or "+internal.AttributeFieldPrefix+"<name> for an additional attribute.")
+	flags.StringP("output", "o", "short",
+		"Output format. One of short, long, json, or, csv. The default output does not handle paged "+
+			"results and must load all results in memory before rendering. Make sure to provide enough "+
+			"filters to limit the result set to a reasonable size or use json or csv output. "+
+			"To download using other tools use metalink for a Metalink 4 document, aria2 for an aria2c "+
+			"input file, or wget or curl for a shell script. These include sizes and checksums, where "+
+			"available, so downloads can be verified. Use geojson or kml to write granule footprints "+
+			"for use in GIS tools; --fields sets the feature properties.")
+
+	cobra.CheckErr(flags.MarkDeprecated("yes", "Not used and will be ignored"))
+}
+
+// addDownloadFlags adds the flags controlling how granules are downloaded, excluding --download
+func addDownloadFlags(flags *pflag.FlagSet) {
 	flags.BoolP("download-clobber", "C", false, "Overwrite any existing files when downloading.")
 	flags.Int("download-concurrency", defaultDownloadConcurrency, "Number of concurrent downloads")
 	flags.BoolP(
@@ -158,8 +245,49 @@ func init() {
 		"Enable skipping files where the remote checksum matches the local checksum in addition "+
 			"to skipping files that exist by name. If the there is no checksum available in the remote "+
 			"metadata or the specified checksum algorithm is not supported, exists checking is done by "+
-			"name only. Currently supported checksum algorithms include MD5, SHA-256, SHA-384, and SHA-512.",
+			"name only. Supported checksum algorithms are "+strings.Join(internal.ChecksumAlgs(), ", ")+".",
 	)
+	flags.Bool("progress", true,
+		"Display download progress, including per-download progress, overall progress, and throughput. "+
+			"If stderr is not a terminal a progress summary is logged periodically instead.")
+	flags.String("download-report", "",
+		"Write a machine-readable report with an entry for every downloaded, skipped, or failed "+
+			"granule to this file, or stdout if -. Regardless of this flag, the exit code is non-zero if "+
+			"any download fails.")
+	flags.String("download-report-format", "json",
+		"Format for --download-report. One of json, for a single JSON array, or ndjson, for one JSON "+
+			"object per line written as downloads complete.")
+	flags.String("download-template", "",
+		"Go template for the download destination of each granule, relative to the download "+
+			"directory, e.g., '{{.ShortName}}/{{.Version}}/{{.Start.Year}}/{{doy .Start}}/{{.Name}}'. "+
+			"Granule fields, e.g., .Name, .Collection, .NativeID, .DayNightFlag, are available as well "+
+			"as .ShortName, .Version, and the temporal range as the times .Start and .End. The doy "+
+			"function formats a time as a 3 digit day of year. If the template renders a directory, "+
+			"i.e., ends with /, the granule name is appended. Directories are created as needed and "+
+			"paths outside of the download directory are rejected.")
+	flags.String("manifest", "",
+		"Record downloaded granules in a manifest database at this path, creating it if necessary. "+
+			"Granules already in the manifest at the same revision are skipped, even if the file no "+
+			"longer exists locally, and granules with a new revision are downloaded again. See the "+
+			"manifest command to list and export records.")
+	flags.Int("download-max-attempts", internal.DefaultRetryPolicy.MaxAttempts,
+		"Maximum number of attempts for each download. Only transient failures, such as network timeouts "+
+			"or HTTP 429 and 5xx responses, are retried. Use 1 to disable retries.")
+	flags.Duration("download-backoff", internal.DefaultRetryPolicy.InitialBackoff,
+		"Initial delay between download attempts. The delay doubles, with random jitter, for each "+
+			"subsequent attempt unless the server provides a Retry-After header.")
+	flags.Duration("download-max-backoff", internal.DefaultRetryPolicy.MaxBackoff,
+		"Maximum delay between download attempts, including delays requested by a Retry-After header.")
+	flags.Bool("direct-access", false,
+		"Download granules using their direct access s3:// urls rather than http urls. Temporary "+
+			"S3 credentials are obtained from the s3credentials endpoint listed in the collection "+
+			"metadata using NASA Earthdata authentication. Direct access is generally only available "+
+			"from within the AWS us-west-2 region.")
+	flags.String("s3-credentials-url", "",
+		"s3credentials endpoint to use with --direct-access rather than the endpoint from the "+
+			"collection metadata.")
+	flags.String("s3-endpoint", "",
+		"URL of a S3 compatible service to use with --direct-access rather than AWS S3.")
 	flags.String("edltoken", "",
 		"Use a NASA EDL token for bearer-based authentication on redirect. Either this or netrc is "+
 			"necessary for NASA Earthdata authentication, which many providers use. See the NASA "+
@@ -168,7 +296,15 @@ func init() {
 		"Use netrc for basic authentication credentials on redirect. Either this or edltoken is "+
 			"necessary for NASA Earthdata authentication, which many providers use. See the NASA "+
 			"Earthdata Authentication above.")
+	flags.Bool("persist-cookies", false,
+		"Save authentication cookies to a file in the user cache directory, readable only by "+
+			"the current user, so netrc login does not have to be repeated on every run.")
+	flags.Duration("cookie-max-age", internal.DefaultCookieMaxAge,
+		"Maximum time persisted cookies are kept, which also applies to cookies without an expiration.")
+}
 
+// addSearchFlags adds the granule search filter flags, using tr for the --timerange value
+func addSearchFlags(flags *pflag.FlagSet, tr *internal.TimeRangesValue) {
 	flags.StringSliceP("nativeid", "N", nil, "Granule native id")
 	flags.StringSliceP("collection", "c", nil,
 		"Collection concept id. Collection concept ids can be found using the 'collections' command. "+
@@ -183,7 +319,13 @@ func init() {
 		"Filter on an approximation of the filename. Must be sepcified with --collection. In CMR metadata "+
 			"terms this searches the granule ur and producer granule id.")
 	flags.StringP("daynight", "D", "", "Day or night grnaules. One of day, night, both, or unspecified")
-	flags.VarP(&timerange, "timerange", "t", "Timerange as <start>,[<end>]")
+	flags.VarP(tr, "timerange", "t", "Timerange as <start>,[<end>], where times are "+internal.TimeExprHelp+
+		". Omit the end for an open ended range. May be provided more than once for granules in any "+
+		"of the ranges.")
+	flags.String("season", "",
+		"Limit results to a day of year window, as <start>,<end>, in every year of --timerange, e.g., "+
+			"182,212 or 07-01,07-31 for every July. The start may be after the end for a window that "+
+			"spans the new year. Requires --timerange with an end.")
 	flags.Float64Slice("polygon", nil,
 		"Polygon points are provided in counter-clockwise order. The last point should match the first point to "+
 			"close the polygon. The values are listed comma separated in longitude latitude order, "+
@@ -193,14 +335,79 @@ func init() {
 	flags.Float64Slice("circle", nil, "Granules overlapping a circle, where the circle is defined as "+
 		"centerlon,centerlat,radius.")
 	flags.Float64Slice("point", nil, "Granules containing point lon,lat.")
-	flags.StringSlice("fields", defaultFields,
-		"Fields to include in output; ignored for --output=short. "+strings.Join(validFields, ", "))
-	flags.StringP("output", "o", "short",
-		"Output format. One of short, long, json, or, csv. The default output does not handle paged "+
-			"results and must load all results in memory before rendering. Make sure to provide enough "+
-			"filters to limit the result set to a reasonable size or use json or csv output.")
+	internal.AddRegionFlag(flags)
+	internal.AddAttributeFlag(flags)
+	flags.String("cloud-cover", "",
+		"Granules with a cloud cover percentage in the range <min>,<max>. Either value may be omitted "+
+			"for an open range, e.g., ,20 for at most 20% cloud cover.")
+	flags.String("orbit-number", "", "Granules for an orbit number, or a range of orbits as <min>,<max>.")
+	flags.String("equator-crossing-longitude", "",
+		"Granules with an equator crossing longitude in the range <min>,<max>. Min may be greater "+
+			"than max for a range crossing the antimeridian.")
+	flags.String("equator-crossing-date", "",
+		"Granules with an equator crossing date in the range <start>,[<end>]; see --timerange for "+
+			"the time formats.")
+}
 
-	cobra.CheckErr(flags.MarkDeprecated("yes", "Not used and will be ignored"))
+// validateSearchFlags checks the search flags added using addSearchFlags
+func validateSearchFlags(flags *pflag.FlagSet) error {
+	if !flags.Changed("collection") &&
+		!flags.Changed("nativeid") &&
+		!flags.Changed("shortname") &&
+		!flags.Changed("filename") {
+		return fmt.Errorf("at least one of --collection, --shortname, --nativeid, or --filename is required")
+	}
+	if flags.Changed("filename") && !flags.Changed("collection") {
+		return fmt.Errorf("--collection is required when using --filename")
+	}
+	return nil
+}
+
+// newDownloadOptions creates download options from --download and the flags added using
+// addDownloadFlags.
+func newDownloadOptions(flags *pflag.FlagSet) (downloadOptions, error) {
+	opts := downloadOptions{}
+	var err error
+	opts.destdir, err = flags.GetString("download")
+	failOnError(err)
+	opts.token, err = flags.GetString("edltoken")
+	failOnError(err)
+	opts.netrc, err = flags.GetBool("netrc")
+	failOnError(err)
+	opts.concurrency, err = flags.GetInt("download-concurrency")
+	failOnError(err)
+	opts.clobber, err = flags.GetBool("download-clobber")
+	failOnError(err)
+	opts.skipByChecksum, err = flags.GetBool("download-skip-checksum")
+	failOnError(err)
+	opts.retryPolicy, err = newRetryPolicy(flags)
+	if err != nil {
+		return opts, err
+	}
+	opts.directAccess, err = flags.GetBool("direct-access")
+	failOnError(err)
+	opts.s3CredentialsURL, err = flags.GetString("s3-credentials-url")
+	failOnError(err)
+	opts.s3Endpoint, err = flags.GetString("s3-endpoint")
+	failOnError(err)
+	opts.persistCookies, err = flags.GetBool("persist-cookies")
+	failOnError(err)
+	opts.cookieMaxAge, err = flags.GetDuration("cookie-max-age")
+	failOnError(err)
+	opts.progress, err = flags.GetBool("progress")
+	failOnError(err)
+	opts.reportPath, err = flags.GetString("download-report")
+	failOnError(err)
+	opts.reportFormat, err = flags.GetString("download-report-format")
+	failOnError(err)
+	opts.manifestPath, err = flags.GetString("manifest")
+	failOnError(err)
+	opts.template, err = flags.GetString("download-template")
+	failOnError(err)
+	if !arrayContains(reportFormats, opts.reportFormat) {
+		return opts, fmt.Errorf("--download-report-format must be one of %s", strings.Join(reportFormats, ", "))
+	}
+	return opts, nil
 }
 
 func do(api *internal.CMRSearchAPI, params *internal.SearchGranuleParams, writerName string, fields []string) error {
@@ -214,11 +421,27 @@ func do(api *internal.CMRSearchAPI, params *internal.SearchGranuleParams, writer
 		writer = jsonWriter
 	case "csv":
 		writer = csvWriter
+	case "metalink":
+		writer = metalinkWriter
+	case "aria2":
+		writer = aria2Writer
+	case "wget":
+		writer = wgetWriter
+	case "curl":
+		writer = curlWriter
+	case "geojson":
+		writer = geojsonWriter
+	case "kml":
+		writer = kmlWriter
 	default:
-		return fmt.Errorf("--output must be one of short, long, json, csv")
+		return fmt.Errorf("--output must be one of short, long, json, csv, metalink, aria2, wget, curl, " +
+			"geojson, kml")
 	}
 
-	zult, err := api.SearchGranules(context.Background(), params)
+	// Stops the search if the writer fails before all results are received
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	zult, err := api.SearchGranules(ctx, params)
 	if err != nil {
 		return err
 	}
@@ -233,6 +456,47 @@ func do(api *internal.CMRSearchAPI, params *internal.SearchGranuleParams, writer
 	return writer(zult, os.Stdout, fields)
 }
 
+func doCount(api *internal.CMRSearchAPI, params *internal.SearchGranuleParams) error {
+	hits, err := api.CountGranules(context.Background(), params)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hits)
+	return nil
+}
+
+func newRetryPolicy(flags *pflag.FlagSet) (internal.RetryPolicy, error) {
+	policy := internal.RetryPolicy{}
+	var err error
+	policy.MaxAttempts, err = flags.GetInt("download-max-attempts")
+	failOnError(err)
+	if policy.MaxAttempts < 1 {
+		return policy, fmt.Errorf("--download-max-attempts must be at least 1")
+	}
+	policy.InitialBackoff, err = flags.GetDuration("download-backoff")
+	failOnError(err)
+	policy.MaxBackoff, err = flags.GetDuration("download-max-backoff")
+	failOnError(err)
+	if policy.InitialBackoff < 0 || policy.MaxBackoff < 0 {
+		return policy, fmt.Errorf("download backoff durations must not be negative")
+	}
+	return policy, nil
+}
+
+// attributeFields returns the output fields for the --attribute filters
+func attributeFields(flags *pflag.FlagSet) []string {
+	fields := []string{}
+	// already validated by newParams
+	attrs, _ := internal.AttributesFromFlags(flags)
+	for _, attr := range attrs {
+		name := internal.AttributeFieldPrefix + attr.Name
+		if !arrayContains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
 func newParams(flags *pflag.FlagSet) (*internal.SearchGranuleParams, error) {
 	params := &internal.SearchGranuleParams{}
 
@@ -275,7 +539,52 @@ func newParams(flags *pflag.FlagSet) (*internal.SearchGranuleParams, error) {
 		params.Filenames(sa...)
 	}
 
-	params.Timerange(*timerange.Start, timerange.End)
+	if flags.Changed("season") {
+		st, err := flags.GetString("season")
+		failOnError(err)
+		season, err := internal.ParseSeason(st)
+		if err != nil {
+			return params, fmt.Errorf("invalid --season: %w", err)
+		}
+		params.Season(season)
+	}
+
+	for _, f := range []struct {
+		name  string
+		parse func(string) (internal.FloatRange, error)
+		set   func(internal.FloatRange) *internal.SearchGranuleParams
+	}{
+		{"cloud-cover", internal.ParseFloatRange, params.CloudCover},
+		{"orbit-number", internal.ParseFloatRange, params.OrbitNumber},
+		{"equator-crossing-longitude", internal.ParseLongitudeRange, params.EquatorCrossingLongitude},
+	} {
+		if !flags.Changed(f.name) {
+			continue
+		}
+		st, err := flags.GetString(f.name)
+		failOnError(err)
+		r, err := f.parse(st)
+		if err != nil {
+			return params, fmt.Errorf("invalid --%s: %w", f.name, err)
+		}
+		f.set(r)
+	}
+
+	if flags.Changed("equator-crossing-date") {
+		st, err := flags.GetString("equator-crossing-date")
+		failOnError(err)
+		tr := &internal.TimeRangeValue{}
+		if err := tr.Set(st); err != nil {
+			return params, fmt.Errorf("invalid --equator-crossing-date: %w", err)
+		}
+		params.EquatorCrossingDate(internal.TimeRange{Start: *tr.Start, End: tr.End})
+	}
+
+	attrs, err := internal.AttributesFromFlags(flags)
+	if err != nil {
+		return params, err
+	}
+	params.Attributes(attrs...)
 
 	a, err := flags.GetFloat64Slice("polygon")
 	failOnError(err)
@@ -293,5 +602,11 @@ func newParams(flags *pflag.FlagSet) (*internal.SearchGranuleParams, error) {
 	failOnError(err)
 	params.Point(a)
 
+	region, err := internal.RegionFromFlags(flags)
+	if err != nil {
+		return params, err
+	}
+	params.Region(region)
+
 	return params, nil
 }
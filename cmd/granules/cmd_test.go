@@ -0,0 +1,38 @@
+package granules
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewParamsEquatorCrossingLongitude(t *testing.T) {
+	parse := func(t *testing.T, val string) (*internal.SearchGranuleParams, error) {
+		t.Helper()
+		tr := internal.NewTimeRangesValue()
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		addSearchFlags(flags, &tr)
+		require.NoError(t, flags.Parse([]string{"--collection=C1-X", "--equator-crossing-longitude=" + val}))
+		return newParams(flags)
+	}
+
+	t.Run("antimeridian", func(t *testing.T) {
+		params, err := parse(t, "170,-170")
+		require.NoError(t, err)
+		query, err := params.Encode()
+		require.NoError(t, err)
+		vals, err := url.ParseQuery(query)
+		require.NoError(t, err)
+		require.Equal(t, "170,-170", vals.Get("equator_crossing_longitude"))
+	})
+
+	for _, val := range []string{"170,", "10,200", "a,1"} {
+		t.Run("invalid "+val, func(t *testing.T) {
+			_, err := parse(t, val)
+			require.Error(t, err)
+		})
+	}
+}
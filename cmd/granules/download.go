@@ -4,8 +4,13 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/cookiejar"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bmflynn/cmrfetch/internal"
 	"github.com/bmflynn/cmrfetch/internal/log"
@@ -30,12 +35,12 @@ func shouldDownload(
 	}
 	if skipByChecksum {
 		if !internal.ChecksumAlgSupported(request.ChecksumAlg) {
-			return true, fmt.Sprintf("exists by name, checksum alg %s supported", request.ChecksumAlg)
+			return true, fmt.Sprintf("exists by name, checksum alg %s not supported", request.ChecksumAlg)
 		}
 		checksum, err := checksummer(request.ChecksumAlg, request.Dest)
 		if err != nil {
 			return false, fmt.Sprintf("exists by name, checksum failed: %s", err)
-		} else if checksum == request.Checksum {
+		} else if internal.ChecksumsEqual(request.ChecksumAlg, checksum, request.Checksum) {
 			return false, "exists by name and checksum"
 		} else {
 			return true, "exists by name, but checksum differs"
@@ -44,20 +49,93 @@ func shouldDownload(
 	return true, ""
 }
 
-func zultsToRequests(granules internal.GranuleResult, destdir string, clobber, skipByChecksum bool) chan internal.DownloadRequest {
-	requests := make(chan internal.DownloadRequest)
-	if !filepath.IsAbs(destdir) {
-		panic("destdir is not absolute")
+// granuleURLFunc returns the url to use to download a granule
+type granuleURLFunc func(internal.Granule) (string, error)
+
+func httpURL(gran internal.Granule) (string, error) {
+	return gran.GetDataURL, nil
+}
+
+// directAccessURLs provides granule direct access urls, making sure the credential store has
+// the s3credentials endpoint for the granule's bucket before the url is used.
+type directAccessURLs struct {
+	ctx   context.Context
+	api   *internal.CMRSearchAPI
+	store *internal.S3CredentialStore
+	// set if the user provided the endpoint, otherwise the endpoint is looked up
+	// from the granule's collection
+	credentialsURL string
+	// collection to endpoint
+	endpoints map[string]string
+}
+
+func (d *directAccessURLs) url(gran internal.Granule) (string, error) {
+	if gran.GetDataDAURL == "" {
+		return "", fmt.Errorf("no direct access url")
 	}
+	if d.credentialsURL != "" {
+		return gran.GetDataDAURL, nil
+	}
+	bucket, _, err := internal.ParseS3URL(gran.GetDataDAURL)
+	if err != nil {
+		return "", err
+	}
+	endpoint, ok := d.endpoints[gran.Collection]
+	if !ok {
+		idx := strings.LastIndex(gran.Collection, "/")
+		if idx < 0 {
+			return "", fmt.Errorf("cannot determine collection for s3 credentials")
+		}
+		endpoint, err = d.api.S3CredentialsURL(d.ctx, gran.Collection[:idx], gran.Collection[idx+1:])
+		if err != nil {
+			return "", err
+		}
+		log.Debug("using s3 credentials endpoint %s for %s", endpoint, gran.Collection)
+		d.endpoints[gran.Collection] = endpoint
+	}
+	d.store.SetEndpoint(bucket, endpoint)
+	return gran.GetDataDAURL, nil
+}
+
+// zultsToRequests creates download requests for granules. Granules that should be downloaded
+// but cannot be are sent to the returned failures channel as failed results, which must be
+// received along with requests.
+func zultsToRequests(
+	granules internal.GranuleResult,
+	destPath destPathFunc,
+	clobber, skipByChecksum bool,
+	granuleURL granuleURLFunc,
+	// called with the reason for any granule that is not downloaded; may be nil
+	onSkip func(internal.DownloadRequest, string),
+) (chan internal.DownloadRequest, chan internal.DownloadResult) {
+	requests := make(chan internal.DownloadRequest)
+	failures := make(chan internal.DownloadResult)
 	go func() {
 		defer close(requests)
+		defer close(failures)
 		for gran := range granules.Ch {
+			// Use grnaule name in dest, b/c who knows what the base of the URL will be
+			dest, err := destPath(gran)
+			if err != nil {
+				// there is no destination, so the granule name identifies the failed download
+				failures <- internal.DownloadResult{URL: gran.GetDataURL, Path: gran.Name, Err: err}
+				continue
+			}
+			url, err := granuleURL(gran)
+			if err != nil {
+				failures <- internal.DownloadResult{
+					URL:  gran.GetDataURL,
+					Path: dest,
+					Err:  fmt.Errorf("no download url: %w", err),
+				}
+				continue
+			}
 			request := internal.DownloadRequest{
-				// Use grnaule name in dest, b/c who knows what the base of the URL will be
-				Dest:        filepath.Join(destdir, gran.Name),
-				URL:         gran.GetDataURL,
+				Dest:        dest,
+				URL:         url,
 				Checksum:    gran.Checksum,
 				ChecksumAlg: gran.ChecksumAlg,
+				Size:        gran.SizeBytes,
 			}
 			ok, reason := shouldDownload(&request, clobber, skipByChecksum, internal.Checksum, internal.Exists)
 			if ok {
@@ -67,23 +145,190 @@ func zultsToRequests(granules internal.GranuleResult, destdir string, clobber, s
 				requests <- request
 			} else {
 				log.Printf("skipping %s, %s", request.Dest, reason)
+				if onSkip != nil {
+					onSkip(request, reason)
+				}
 			}
 		}
 	}()
-	return requests
+	return requests, failures
 }
 
-func doDownload(
+// mergeResults sends all results from a and b to the returned channel, which is closed once
+// both are closed
+func mergeResults(a, b chan internal.DownloadResult) chan internal.DownloadResult {
+	merged := make(chan internal.DownloadResult)
+	wg := &sync.WaitGroup{}
+	forward := func(ch chan internal.DownloadResult) {
+		defer wg.Done()
+		for zult := range ch {
+			merged <- zult
+		}
+	}
+	wg.Add(2)
+	go forward(a)
+	go forward(b)
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	return merged
+}
+
+// queueWithProgress adds requests to the renderer totals as they are queued
+func queueWithProgress(requests chan internal.DownloadRequest, renderer *progressRenderer) chan internal.DownloadRequest {
+	queued := make(chan internal.DownloadRequest)
+	go func() {
+		defer close(queued)
+		for req := range requests {
+			renderer.Queued(req)
+			queued <- req
+		}
+	}()
+	return queued
+}
+
+// manifestRecorder skips granules already in the manifest at the same revision and records
+// successful downloads. Safe for concurrent use.
+type manifestRecorder struct {
+	mu       sync.Mutex
+	manifest *internal.Manifest
+	destPath destPathFunc
+	clobber  bool
+	onSkip   func(internal.DownloadRequest, string)
+	// granules queued for download, by destination path
+	queued map[string]internal.Granule
+}
+
+func newManifestRecorder(
+	manifest *internal.Manifest,
+	destPath destPathFunc,
+	clobber bool,
+	onSkip func(internal.DownloadRequest, string),
+) *manifestRecorder {
+	return &manifestRecorder{
+		manifest: manifest,
+		destPath: destPath,
+		clobber:  clobber,
+		onSkip:   onSkip,
+		queued:   map[string]internal.Granule{},
+	}
+}
+
+// keep returns whether gran needs to be downloaded
+func (r *manifestRecorder) keep(gran internal.Granule) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.clobber {
+		rec, found, err := r.manifest.Get(gran.ConceptID, gran.Name)
+		switch {
+		case err != nil:
+			log.Printf("WARNING: manifest lookup failed for %s: %s", gran.Name, err)
+		case found && rec.RevisionID == gran.RevisionID:
+			reason := fmt.Sprintf("in manifest at revision %s", rec.RevisionID)
+			log.Printf("skipping %s, %s", gran.Name, reason)
+			if r.onSkip != nil {
+				dest, _ := r.destPath(gran)
+				r.onSkip(internal.DownloadRequest{URL: rec.URL, Dest: dest}, reason)
+			}
+			return false
+		case found:
+			log.Debug("downloading %s, revision changed from %s to %s", gran.Name, rec.RevisionID, gran.RevisionID)
+		}
+	}
+	dest, err := r.destPath(gran)
+	if err != nil {
+		// nothing to record, the download fails when the request is created
+		return true
+	}
+	r.queued[dest] = gran
+	return true
+}
+
+// record adds a successful download to the manifest
+func (r *manifestRecorder) record(zult internal.DownloadResult) error {
+	r.mu.Lock()
+	gran, ok := r.queued[zult.Path]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no granule for %s", zult.Path)
+	}
+	return r.manifest.Put(internal.ManifestRecord{
+		ConceptID:   gran.ConceptID,
+		RevisionID:  gran.RevisionID,
+		Name:        gran.Name,
+		Collection:  gran.Collection,
+		URL:         zult.URL,
+		Path:        zult.Path,
+		Size:        zult.Size,
+		Checksum:    zult.Checksum,
+		ChecksumAlg: gran.ChecksumAlg,
+		FetchedAt:   time.Now().UTC(),
+	})
+}
+
+type downloadOptions struct {
+	destdir        string
+	token          string
+	netrc          bool
+	clobber        bool
+	yes            bool
+	skipByChecksum bool
+	concurrency    int
+	retryPolicy    internal.RetryPolicy
+
+	// Download using direct access s3 urls rather than http urls
+	directAccess bool
+	// s3credentials endpoint to use rather than the one from collection metadata
+	s3CredentialsURL string
+	// S3 compatible service url to use rather than AWS
+	s3Endpoint string
+
+	// Save authentication cookies to disk for use by later runs
+	persistCookies bool
+	cookieMaxAge   time.Duration
+
+	// Display download progress
+	progress bool
+
+	// Write a report of all download results to this path, or stdout if "-"
+	reportPath   string
+	reportFormat string
+
+	// Manifest database of downloaded granules, if any
+	manifestPath string
+
+	// Template for download destinations relative to destdir, if any
+	template string
+
+	// called for every download result; may be nil
+	onResult func(internal.DownloadResult)
+}
+
+// newDestPath returns the download destination for granules using opts.destdir and
+// opts.template, if any.
+func newDestPath(opts downloadOptions) (destPathFunc, error) {
+	destdir, err := filepath.Abs(opts.destdir)
+	if err != nil {
+		return nil, fmt.Errorf("getting absolute path for %s", opts.destdir)
+	}
+	if opts.template != "" {
+		return templateDestPath(destdir, opts.template)
+	}
+	return flatDestPath(destdir), nil
+}
+
+// searchForDownload searches for granules to download, prompting for confirmation if there are
+// a large number of results unless yes is set.
+func searchForDownload(
 	ctx context.Context,
 	api *internal.CMRSearchAPI,
 	params *internal.SearchGranuleParams,
-	destdir, token string,
-	netrc, clobber, yes, skipByChecksum bool,
-	concurrency int,
-) error {
-	zult, err := api.SearchGranules(context.Background(), params)
+	yes bool,
+) (internal.GranuleResult, error) {
+	zult, err := api.SearchGranules(ctx, params)
 	if err != nil {
-		return err
+		return zult, err
 	}
 
 	log.Printf("%v results\n", zult.Hits())
@@ -91,10 +336,20 @@ func doDownload(
 	if !yes && zult.Hits() > maxResultsWithoutPrompt {
 		fmt.Printf("There are more than %v, CTRL-C to cancel or ENTER to continue\n", maxResultsWithoutPrompt)
 		if _, err := bufio.NewReader(os.Stdin).ReadBytes('\n'); err != nil {
-			return err
+			return zult, err
 		}
 	}
+	return zult, nil
+}
 
+func doDownload(
+	ctx context.Context,
+	api *internal.CMRSearchAPI,
+	env internal.CMREnv,
+	zult internal.GranuleResult,
+	opts downloadOptions,
+) error {
+	destdir := opts.destdir
 	if internal.Exists(destdir) {
 		switch {
 		case !internal.IsDir(destdir):
@@ -109,27 +364,131 @@ func doDownload(
 		}
 	}
 
-	token = internal.ResolveEDLToken(token)
-	log.Debug("auth netrc:%v edltoken:%v\n", netrc, token != "")
+	token := internal.ResolveEDLToken(opts.token)
+	log.Debug("auth netrc:%v edltoken:%v\n", opts.netrc, token != "")
+	if token == "" && opts.netrc {
+		if ok, err := internal.NetrcHasMachine(env.EDLHost); err != nil {
+			log.Debug("checking netrc: %s", err)
+		} else if !ok {
+			log.Printf("WARNING: no netrc entry for %s, authentication will likely fail", env.EDLHost)
+		}
+	}
+
+	// All fetchers share a jar so authentication only has to happen once
+	var jar http.CookieJar
+	var err error
+	if opts.persistCookies {
+		fpath, err := internal.DefaultCookieJarPath()
+		if err != nil {
+			return fmt.Errorf("getting cookie jar path: %w", err)
+		}
+		jar, err = internal.NewPersistentJar(fpath, opts.cookieMaxAge)
+		if err != nil {
+			return err
+		}
+		log.Debug("using persistent cookie jar %s", fpath)
+	} else {
+		jar, err = cookiejar.New(nil)
+		if err != nil {
+			return fmt.Errorf("creating cookiejar: %w", err)
+		}
+	}
 
 	fetcherFactory := func() (internal.Fetcher, error) {
-		fetcher, err := internal.NewHTTPFetcher(netrc, token)
+		fetcher, err := internal.NewHTTPFetcherWithJar(opts.netrc, token, jar)
 		return fetcher.Fetch, err
 	}
-	destdir, err = filepath.Abs(destdir)
+	granuleURL := httpURL
+	if opts.directAccess {
+		// Credentials are fetched via Earthdata Login authenticated HTTP
+		credsFetcher, err := internal.NewHTTPFetcherWithJar(opts.netrc, token, jar)
+		if err != nil {
+			return fmt.Errorf("init s3 credentials fetcher: %w", err)
+		}
+		store := internal.NewS3CredentialStore(credsFetcher.Fetch, opts.s3CredentialsURL)
+		fetcherFactory = func() (internal.Fetcher, error) {
+			return internal.NewS3Fetcher(store, "", opts.s3Endpoint).Fetch, nil
+		}
+		da := &directAccessURLs{
+			ctx:            ctx,
+			api:            api,
+			store:          store,
+			credentialsURL: opts.s3CredentialsURL,
+			endpoints:      map[string]string{},
+		}
+		granuleURL = da.url
+	}
+
+	destPath, err := newDestPath(opts)
 	if err != nil {
-		return fmt.Errorf("getting absolute path for %s", destdir)
+		return err
+	}
+	var report *downloadReport
+	var onSkip func(internal.DownloadRequest, string)
+	if opts.reportPath != "" {
+		report, err = openDownloadReport(opts.reportPath, opts.reportFormat)
+		if err != nil {
+			return err
+		}
+		// Closed below to check the error, this makes sure the report is complete on early return
+		defer report.Close()
+		onSkip = func(req internal.DownloadRequest, reason string) {
+			if err := report.Skipped(req, reason); err != nil {
+				log.Printf("WARNING: writing download report: %s", err)
+			}
+		}
+	}
+
+	var recorder *manifestRecorder
+	if opts.manifestPath != "" {
+		manifest, err := internal.OpenManifest(opts.manifestPath)
+		if err != nil {
+			return err
+		}
+		defer manifest.Close()
+		recorder = newManifestRecorder(manifest, destPath, opts.clobber, onSkip)
+		zult = zult.Filter(recorder.keep)
+	}
+
+	requests, failures := zultsToRequests(zult, destPath, opts.clobber, opts.skipByChecksum, granuleURL, onSkip)
+
+	var progress internal.ProgressTracker
+	if opts.progress {
+		renderer := newProgressRenderer(os.Stderr, isTerminal(os.Stderr))
+		renderer.SetTotal(zult.Hits())
+		requests = queueWithProgress(requests, renderer)
+		// Route log output through the renderer so it doesn't clobber the display
+		log.SetOutput(renderer)
+		defer log.SetOutput(os.Stderr)
+		renderer.Run()
+		defer renderer.Stop()
+		progress = renderer
 	}
-	requests := zultsToRequests(zult, destdir, clobber, skipByChecksum)
-	results, err := internal.FetchConcurrentWithContext(ctx, requests, fetcherFactory, concurrency)
+
+	results, err := internal.FetchConcurrentWithContext(ctx, requests, fetcherFactory, opts.concurrency, opts.retryPolicy, progress)
 	if err != nil {
 		return fmt.Errorf("init fetcher: %s", err)
 	}
-
-	for zult := range results {
+	var count, failed int
+	for zult := range mergeResults(results, failures) {
+		count++
+		if opts.onResult != nil {
+			opts.onResult(zult)
+		}
+		if report != nil {
+			if err := report.Result(zult); err != nil {
+				log.Printf("WARNING: writing download report: %s", err)
+			}
+		}
+		if recorder != nil && zult.Err == nil {
+			if err := recorder.record(zult); err != nil {
+				log.Printf("WARNING: recording %s in manifest: %s", zult.Path, err)
+			}
+		}
 		switch {
 		case zult.Err != nil:
-			log.Printf("failed! %s error=%s", zult.URL, zult.Err)
+			failed++
+			log.Printf("failed! %s attempts=%d error=%s", zult.URL, zult.Attempts, zult.Err)
 			continue
 		case zult.Err == nil:
 			log.Printf(
@@ -141,5 +500,16 @@ func doDownload(
 			log.Printf("checksum skipped: %s", zult.ChecksumVerificationSkipped)
 		}
 	}
+	if report != nil {
+		if err := report.Close(); err != nil {
+			return fmt.Errorf("writing download report: %w", err)
+		}
+	}
+	if err := zult.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d downloads failed", failed, count)
+	}
 	return nil
 }
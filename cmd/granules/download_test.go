@@ -1,9 +1,12 @@
 package granules
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"github.com/bmflynn/cmrfetch/internal"
@@ -57,7 +60,163 @@ func Test_zultsToRequests(t *testing.T) {
 		Name: "filename.ext",
 	}
 
-	req := <-zultsToRequests(granules, tmpdir, false, false)
+	requests, _ := zultsToRequests(granules, flatDestPath(tmpdir), false, false, httpURL, nil)
+	req := <-requests
 
 	require.Equal(t, path.Base(req.Dest), "filename.ext")
 }
+
+func Test_zultsToRequestsSkipped(t *testing.T) {
+	tmpdir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpdir, "exists.ext"), []byte{}, 0o644))
+
+	granules := internal.GranuleResult{
+		Ch: make(chan internal.Granule, 1),
+	}
+	granules.Ch <- internal.Granule{
+		Name:        "exists.ext",
+		Checksum:    "d41d8cd98f00b204e9800998ecf8427e",
+		ChecksumAlg: "MD5",
+	}
+	close(granules.Ch)
+
+	skipped := map[string]string{}
+	requests, failures := zultsToRequests(granules, flatDestPath(tmpdir), false, true, httpURL, func(req internal.DownloadRequest, reason string) {
+		skipped[path.Base(req.Dest)] = reason
+	})
+	for range requests {
+		t.Fatal("expected no requests")
+	}
+	for range failures {
+		t.Fatal("expected no failures")
+	}
+
+	require.Equal(t, map[string]string{"exists.ext": "exists by name and checksum"}, skipped)
+}
+
+func Test_zultsToRequestsFailed(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	granules := internal.GranuleResult{
+		Ch: make(chan internal.Granule, 1),
+	}
+	granules.Ch <- internal.Granule{Name: "nourl.ext", GetDataURL: "https://host/nourl.ext"}
+	close(granules.Ch)
+
+	noURL := func(internal.Granule) (string, error) { return "", fmt.Errorf("no direct access url") }
+	skipped := map[string]string{}
+	requests, failures := zultsToRequests(granules, flatDestPath(tmpdir), false, false, noURL, func(req internal.DownloadRequest, reason string) {
+		skipped[path.Base(req.Dest)] = reason
+	})
+
+	zult := <-failures
+	for range requests {
+		t.Fatal("expected no requests")
+	}
+
+	require.Equal(t, "https://host/nourl.ext", zult.URL)
+	require.Equal(t, path.Join(tmpdir, "nourl.ext"), zult.Path)
+	require.ErrorContains(t, zult.Err, "no direct access url")
+	require.Empty(t, skipped, "url errors are failures, not skips")
+}
+
+func Test_zultsToRequestsTemplateFailed(t *testing.T) {
+	destPath, err := templateDestPath(t.TempDir(), "../{{.Name}}")
+	require.NoError(t, err)
+
+	granules := internal.GranuleResult{
+		Ch: make(chan internal.Granule, 1),
+	}
+	granules.Ch <- internal.Granule{Name: "outside.ext", GetDataURL: "https://host/outside.ext"}
+	close(granules.Ch)
+
+	requests, failures := zultsToRequests(granules, destPath, false, false, httpURL, func(internal.DownloadRequest, string) {
+		t.Fatal("template errors are failures, not skips")
+	})
+	zult := <-failures
+	for range requests {
+		t.Fatal("expected no requests")
+	}
+
+	require.Equal(t, "https://host/outside.ext", zult.URL)
+	require.Equal(t, "outside.ext", zult.Path)
+	require.ErrorContains(t, zult.Err, "outside of")
+}
+
+func TestManifestRecorder(t *testing.T) {
+	manifest, err := internal.OpenManifest(path.Join(t.TempDir(), "manifest.db"))
+	require.NoError(t, err)
+	defer manifest.Close()
+
+	require.NoError(t, manifest.Put(internal.ManifestRecord{ConceptID: "G1-P", RevisionID: "1", Name: "a.nc"}))
+	require.NoError(t, manifest.Put(internal.ManifestRecord{ConceptID: "G2-P", RevisionID: "1", Name: "b.nc"}))
+
+	skipped := map[string]string{}
+	recorder := newManifestRecorder(manifest, flatDestPath("/data"), false, func(req internal.DownloadRequest, reason string) {
+		skipped[path.Base(req.Dest)] = reason
+	})
+
+	require.False(t, recorder.keep(internal.Granule{ConceptID: "G1-P", RevisionID: "1", Name: "a.nc"}))
+	require.Equal(t, map[string]string{"a.nc": "in manifest at revision 1"}, skipped)
+	require.True(t, recorder.keep(internal.Granule{ConceptID: "G2-P", RevisionID: "2", Name: "b.nc"}),
+		"new revision should be downloaded")
+	require.True(t, recorder.keep(internal.Granule{ConceptID: "G3-P", RevisionID: "1", Name: "c.nc"}))
+
+	require.NoError(t, recorder.record(internal.DownloadResult{Path: "/data/b.nc", Size: 10, Checksum: "xxx"}))
+	rec, found, err := manifest.Get("G2-P", "b.nc")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "2", rec.RevisionID)
+	require.Equal(t, "/data/b.nc", rec.Path)
+	require.Equal(t, int64(10), rec.Size)
+	require.False(t, rec.FetchedAt.IsZero())
+
+	require.Error(t, recorder.record(internal.DownloadResult{Path: "/data/unknown.nc"}))
+
+	t.Run("renaming template", func(t *testing.T) {
+		destPath, err := templateDestPath("/data", "{{.Collection}}/x_{{.Name}}")
+		require.NoError(t, err)
+		recorder := newManifestRecorder(manifest, destPath, false, nil)
+
+		// same name in different collections
+		require.True(t, recorder.keep(internal.Granule{ConceptID: "G4-P", RevisionID: "1", Name: "d.nc", Collection: "A/1"}))
+		require.True(t, recorder.keep(internal.Granule{ConceptID: "G5-P", RevisionID: "1", Name: "d.nc", Collection: "B/1"}))
+
+		require.NoError(t, recorder.record(internal.DownloadResult{Path: "/data/B/1/x_d.nc"}))
+		require.NoError(t, recorder.record(internal.DownloadResult{Path: "/data/A/1/x_d.nc"}))
+		rec, found, err := manifest.Get("G4-P", "d.nc")
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, "/data/A/1/x_d.nc", rec.Path)
+		rec, found, err = manifest.Get("G5-P", "d.nc")
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t, "/data/B/1/x_d.nc", rec.Path)
+	})
+
+	t.Run("clobber ignores manifest", func(t *testing.T) {
+		recorder := newManifestRecorder(manifest, flatDestPath("/data"), true, nil)
+		require.True(t, recorder.keep(internal.Granule{ConceptID: "G1-P", RevisionID: "1", Name: "a.nc"}))
+	})
+}
+
+func Test_doDownloadReportClosedOnError(t *testing.T) {
+	dir := t.TempDir()
+	reportPath := filepath.Join(dir, "report.json")
+	opts := downloadOptions{
+		destdir:      filepath.Join(dir, "data"),
+		concurrency:  1,
+		reportPath:   reportPath,
+		reportFormat: "json",
+		// a directory cannot be opened as a manifest
+		manifestPath: dir,
+	}
+
+	err := doDownload(context.Background(), internal.NewCMRSearchAPI(), internal.CMREnv{}, internal.GranuleResult{}, opts)
+	require.Error(t, err)
+
+	dat, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+	entries := []reportEntry{}
+	require.NoError(t, json.Unmarshal(dat, &entries), "report should be complete")
+}
@@ -0,0 +1,262 @@
+package granules
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bmflynn/cmrfetch/internal"
+)
+
+// Output writers for granule footprints
+
+// splitRectangle splits a rectangle crossing the antimeridian into east and west parts, as
+// neither GeoJSON nor KML support rectangles where west > east.
+func splitRectangle(r internal.BoundingRectangle) []internal.BoundingRectangle {
+	if r.West <= r.East {
+		return []internal.BoundingRectangle{r}
+	}
+	return []internal.BoundingRectangle{
+		{West: r.West, North: r.North, East: 180, South: r.South},
+		{West: -180, North: r.North, East: r.East, South: r.South},
+	}
+}
+
+// fieldString formats a granule field value for formats that only support string values
+func fieldString(val any) string {
+	if s, ok := val.(string); ok {
+		return s
+	}
+	dat, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+	return string(dat)
+}
+
+// properties returns the granule fields other than geometry, which is already provided by the
+// feature itself.
+func properties(gran internal.Granule, fields []string) map[string]any {
+	props := granuleToMap(gran, fields)
+	delete(props, "geometry")
+	return props
+}
+
+type geojsonGeometry struct {
+	Type        string            `json:"type"`
+	Coordinates any               `json:"coordinates,omitempty"`
+	Geometries  []geojsonGeometry `json:"geometries,omitempty"`
+}
+
+type geojsonFeature struct {
+	Type       string           `json:"type"`
+	ID         string           `json:"id,omitempty"`
+	Geometry   *geojsonGeometry `json:"geometry"`
+	Properties map[string]any   `json:"properties"`
+}
+
+func geojsonPosition(p internal.Point) []float64 {
+	return []float64{p.Lon, p.Lat}
+}
+
+func geojsonPositions(points []internal.Point) [][]float64 {
+	positions := [][]float64{}
+	for _, p := range points {
+		positions = append(positions, geojsonPosition(p))
+	}
+	return positions
+}
+
+// newGeoJSONGeometry returns the geometry for geom, a GeometryCollection if geom has more than
+// one shape, or nil if geom is empty.
+func newGeoJSONGeometry(geom internal.Geometry) *geojsonGeometry {
+	parts := []geojsonGeometry{}
+	for _, p := range geom.Points {
+		parts = append(parts, geojsonGeometry{Type: "Point", Coordinates: geojsonPosition(p)})
+	}
+	for _, line := range geom.Lines {
+		parts = append(parts, geojsonGeometry{Type: "LineString", Coordinates: geojsonPositions(line)})
+	}
+	for _, rect := range geom.BoundingRectangles {
+		rects := splitRectangle(rect)
+		if len(rects) == 1 {
+			parts = append(parts, geojsonGeometry{
+				Type:        "Polygon",
+				Coordinates: [][][]float64{geojsonPositions(rect.Ring())},
+			})
+			continue
+		}
+		coords := [][][][]float64{}
+		for _, r := range rects {
+			coords = append(coords, [][][]float64{geojsonPositions(r.Ring())})
+		}
+		parts = append(parts, geojsonGeometry{Type: "MultiPolygon", Coordinates: coords})
+	}
+	for _, poly := range geom.GPolygons {
+		rings := [][][]float64{geojsonPositions(poly.Boundary)}
+		for _, hole := range poly.Holes {
+			rings = append(rings, geojsonPositions(hole))
+		}
+		parts = append(parts, geojsonGeometry{Type: "Polygon", Coordinates: rings})
+	}
+
+	switch len(parts) {
+	case 0:
+		return nil
+	case 1:
+		return &parts[0]
+	default:
+		return &geojsonGeometry{Type: "GeometryCollection", Geometries: parts}
+	}
+}
+
+// geojsonWriter writes a GeoJSON (RFC 7946) FeatureCollection with a feature per granule file.
+// Features are streamed so the collection is not complete until all results are written.
+func geojsonWriter(zult internal.GranuleResult, w io.Writer, fields []string) error {
+	if _, err := io.WriteString(w, `{"type":"FeatureCollection","features":[`+"\n"); err != nil {
+		return err
+	}
+	first := true
+	for gran := range zult.Ch {
+		dat, err := json.Marshal(geojsonFeature{
+			Type:       "Feature",
+			ID:         gran.ConceptID + "/" + gran.Name,
+			Geometry:   newGeoJSONGeometry(gran.Geometry),
+			Properties: properties(gran, fields),
+		})
+		if err != nil {
+			return fmt.Errorf("encoding feature: %w", err)
+		}
+		if !first {
+			dat = append([]byte(",\n"), dat...)
+		}
+		first = false
+		if _, err := w.Write(dat); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "\n]}\n"); err != nil {
+		return err
+	}
+	return zult.Err()
+}
+
+type kmlLinearRing struct {
+	Coordinates string `xml:"LinearRing>coordinates"`
+}
+
+type kmlPolygon struct {
+	Outer kmlLinearRing   `xml:"outerBoundaryIs"`
+	Inner []kmlLinearRing `xml:"innerBoundaryIs"`
+}
+
+type kmlMultiGeometry struct {
+	Points      []string     `xml:"Point>coordinates"`
+	LineStrings []string     `xml:"LineString>coordinates"`
+	Polygons    []kmlPolygon `xml:"Polygon"`
+}
+
+func (g *kmlMultiGeometry) count() int {
+	return len(g.Points) + len(g.LineStrings) + len(g.Polygons)
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlTimeSpan struct {
+	Begin string `xml:"begin,omitempty"`
+	End   string `xml:"end,omitempty"`
+}
+
+type kmlPlacemark struct {
+	XMLName       xml.Name          `xml:"Placemark"`
+	ID            string            `xml:"id,attr,omitempty"`
+	Name          string            `xml:"name"`
+	TimeSpan      *kmlTimeSpan      `xml:"TimeSpan,omitempty"`
+	Data          []kmlData         `xml:"ExtendedData>Data"`
+	Point         string            `xml:"Point>coordinates,omitempty"`
+	LineString    string            `xml:"LineString>coordinates,omitempty"`
+	Polygon       *kmlPolygon       `xml:"Polygon,omitempty"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+func kmlCoordinates(points []internal.Point) string {
+	coords := []string{}
+	for _, p := range points {
+		coords = append(coords, fmt.Sprintf("%v,%v", p.Lon, p.Lat))
+	}
+	return strings.Join(coords, " ")
+}
+
+// setGeometry sets the placemark geometry to geom, using a MultiGeometry if geom has more than
+// one shape.
+func (pm *kmlPlacemark) setGeometry(geom internal.Geometry) {
+	multi := &kmlMultiGeometry{}
+	for _, p := range geom.Points {
+		multi.Points = append(multi.Points, kmlCoordinates([]internal.Point{p}))
+	}
+	for _, line := range geom.Lines {
+		multi.LineStrings = append(multi.LineStrings, kmlCoordinates(line))
+	}
+	for _, rect := range geom.BoundingRectangles {
+		for _, r := range splitRectangle(rect) {
+			multi.Polygons = append(multi.Polygons, kmlPolygon{
+				Outer: kmlLinearRing{kmlCoordinates(r.Ring())},
+			})
+		}
+	}
+	for _, poly := range geom.GPolygons {
+		kpoly := kmlPolygon{Outer: kmlLinearRing{kmlCoordinates(poly.Boundary)}}
+		for _, hole := range poly.Holes {
+			kpoly.Inner = append(kpoly.Inner, kmlLinearRing{kmlCoordinates(hole)})
+		}
+		multi.Polygons = append(multi.Polygons, kpoly)
+	}
+
+	switch {
+	case multi.count() > 1:
+		pm.MultiGeometry = multi
+	case len(multi.Points) == 1:
+		pm.Point = multi.Points[0]
+	case len(multi.LineStrings) == 1:
+		pm.LineString = multi.LineStrings[0]
+	case len(multi.Polygons) == 1:
+		pm.Polygon = &multi.Polygons[0]
+	}
+}
+
+// kmlWriter writes a KML 2.2 document with a placemark per granule file. Placemarks include
+// the granule time range so results may be animated in tools that support it.
+func kmlWriter(zult internal.GranuleResult, w io.Writer, fields []string) error {
+	_, err := fmt.Fprintf(w, "%s<kml xmlns=\"http://www.opengis.net/kml/2.2\">\n"+
+		"  <Document>\n    <name>cmrfetch granules</name>\n", xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("    ", "  ")
+	for gran := range zult.Ch {
+		pm := kmlPlacemark{ID: gran.ConceptID, Name: gran.Name}
+		if len(gran.TimeRange) == 2 && (gran.TimeRange[0] != "" || gran.TimeRange[1] != "") {
+			pm.TimeSpan = &kmlTimeSpan{Begin: gran.TimeRange[0], End: gran.TimeRange[1]}
+		}
+		props := properties(gran, fields)
+		for _, name := range fields {
+			if val, ok := props[name]; ok {
+				pm.Data = append(pm.Data, kmlData{Name: name, Value: fieldString(val)})
+			}
+		}
+		pm.setGeometry(gran.Geometry)
+		if err := enc.Encode(pm); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "\n  </Document>\n</kml>\n"); err != nil {
+		return err
+	}
+	return zult.Err()
+}
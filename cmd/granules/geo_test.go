@@ -0,0 +1,127 @@
+package granules
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+var geoGranules = []internal.Granule{
+	{
+		Name:      "a.nc",
+		ConceptID: "G1-X",
+		TimeRange: []string{"2023-01-01T00:00:00Z", "2023-01-01T00:05:00Z"},
+		Geometry: internal.Geometry{
+			GPolygons: []internal.GPolygon{{
+				Boundary: []internal.Point{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 0}},
+			}},
+		},
+	},
+	{
+		Name:      "b.nc",
+		ConceptID: "G2-X",
+		Geometry: internal.Geometry{
+			Points:             []internal.Point{{Lon: 1, Lat: 2}},
+			BoundingRectangles: []internal.BoundingRectangle{{West: 170, North: 10, East: -170, South: -10}},
+		},
+	},
+	{
+		Name:      "c.nc",
+		ConceptID: "G3-X",
+	},
+}
+
+func TestSplitRectangle(t *testing.T) {
+	rect := internal.BoundingRectangle{West: -10, North: 10, East: 10, South: -10}
+	require.Equal(t, []internal.BoundingRectangle{rect}, splitRectangle(rect))
+
+	rects := splitRectangle(internal.BoundingRectangle{West: 170, North: 10, East: -170, South: -10})
+	require.Equal(t, []internal.BoundingRectangle{
+		{West: 170, North: 10, East: 180, South: -10},
+		{West: -180, North: 10, East: -170, South: -10},
+	}, rects)
+}
+
+func TestGeoJSONWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := geojsonWriter(newTestResult(geoGranules...), buf, []string{"name", "timerange", "geometry"})
+	require.NoError(t, err)
+
+	doc := struct {
+		Type     string
+		Features []struct {
+			Type       string
+			ID         string
+			Geometry   *geojsonGeometry
+			Properties map[string]any
+		}
+	}{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc), buf.String())
+	require.Equal(t, "FeatureCollection", doc.Type)
+	require.Len(t, doc.Features, 3)
+
+	feat := doc.Features[0]
+	require.Equal(t, "Feature", feat.Type)
+	require.Equal(t, "G1-X/a.nc", feat.ID)
+	require.Equal(t, "Polygon", feat.Geometry.Type)
+	require.Equal(t, []any{[]any{
+		[]any{0.0, 0.0}, []any{10.0, 0.0}, []any{10.0, 10.0}, []any{0.0, 0.0},
+	}}, feat.Geometry.Coordinates)
+	require.Equal(t, map[string]any{
+		"name":      "a.nc",
+		"timerange": []any{"2023-01-01T00:00:00Z", "2023-01-01T00:05:00Z"},
+	}, feat.Properties, "geometry should not be a property")
+
+	feat = doc.Features[1]
+	require.Equal(t, "GeometryCollection", feat.Geometry.Type)
+	require.Len(t, feat.Geometry.Geometries, 2)
+	require.Equal(t, "Point", feat.Geometry.Geometries[0].Type)
+	require.Equal(t, "MultiPolygon", feat.Geometry.Geometries[1].Type, "antimeridian rectangle is split")
+
+	require.Nil(t, doc.Features[2].Geometry)
+
+	t.Run("empty", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, geojsonWriter(newTestResult(), buf, nil))
+		require.True(t, json.Valid(buf.Bytes()), buf.String())
+	})
+}
+
+func TestKMLWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := kmlWriter(newTestResult(geoGranules...), buf, []string{"name", "timerange"})
+	require.NoError(t, err)
+
+	doc := struct {
+		XMLName    xml.Name       `xml:"http://www.opengis.net/kml/2.2 kml"`
+		Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+	}{}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc), buf.String())
+	require.Len(t, doc.Placemarks, 3)
+
+	pm := doc.Placemarks[0]
+	require.Equal(t, "a.nc", pm.Name)
+	require.Equal(t, &kmlTimeSpan{Begin: "2023-01-01T00:00:00Z", End: "2023-01-01T00:05:00Z"}, pm.TimeSpan)
+	require.Equal(t, []kmlData{
+		{Name: "name", Value: "a.nc"},
+		{Name: "timerange", Value: `["2023-01-01T00:00:00Z","2023-01-01T00:05:00Z"]`},
+	}, pm.Data)
+	require.NotNil(t, pm.Polygon)
+	require.Equal(t, "0,0 10,0 10,10 0,0", pm.Polygon.Outer.Coordinates)
+	require.Nil(t, pm.MultiGeometry)
+
+	pm = doc.Placemarks[1]
+	require.Nil(t, pm.TimeSpan)
+	require.NotNil(t, pm.MultiGeometry)
+	require.Equal(t, []string{"1,2"}, pm.MultiGeometry.Points)
+	require.Len(t, pm.MultiGeometry.Polygons, 2, "antimeridian rectangle is split")
+
+	pm = doc.Placemarks[2]
+	require.Nil(t, pm.Polygon)
+	require.Nil(t, pm.MultiGeometry)
+	require.Empty(t, pm.Point)
+}
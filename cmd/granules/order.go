@@ -1,31 +1,32 @@
 package granules
 
-// TODO: impl sortBy
-// var sortByFields = []string{
-// 	"entry_title",
-// 	"data_size",
-// 	"granule_ur",
-// 	"producer_granule_id",
-// 	"project",
-// 	"provider",
-// 	"short_name",
-// 	"start_date",
-// 	"end_date",
-// 	"version",
-// 	"platform",
-// 	"instrument",
-// 	"sensor",
-// 	"day_night_flag",
-// 	"cloud_cover",
-// 	"revision_date",
-// }
-//
-// func validSortField(val string) bool {
-// 	val = strings.ReplaceAll(val, "-", "")
-// 	for _, s := range sortByFields {
-// 		if val == s {
-// 			return true
-// 		}
-// 	}
-// 	return false
-// }
+import "strings"
+
+// sortByFields are the CMR granule sort keys
+var sortByFields = []string{
+	"entry_title",
+	"data_size",
+	"granule_ur",
+	"producer_granule_id",
+	"project",
+	"provider",
+	"short_name",
+	"start_date",
+	"end_date",
+	"version",
+	"platform",
+	"instrument",
+	"sensor",
+	"day_night_flag",
+	"cloud_cover",
+	"revision_date",
+}
+
+// validSortField returns true if val is a sort key, optionally prefixed with - for descending
+// or + for ascending order
+func validSortField(val string) bool {
+	if strings.HasPrefix(val, "-") || strings.HasPrefix(val, "+") {
+		val = val[1:]
+	}
+	return arrayContains(sortByFields, val)
+}
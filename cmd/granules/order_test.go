@@ -0,0 +1,16 @@
+package granules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidSortField(t *testing.T) {
+	for _, key := range []string{"start_date", "-start_date", "+start_date", "cloud_cover"} {
+		require.True(t, validSortField(key), key)
+	}
+	for _, key := range []string{"", "-", "start", "--start_date", "-+start_date", "shortname"} {
+		require.False(t, validSortField(key), key)
+	}
+}
@@ -0,0 +1,244 @@
+package granules
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal"
+)
+
+const (
+	ttyProgressInterval = 500 * time.Millisecond
+	logProgressInterval = 30 * time.Second
+)
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+type workerProgress struct {
+	name     string
+	size     int64
+	expected int64
+}
+
+// progressRenderer is a internal.ProgressTracker that renders live download progress. On a
+// terminal per-worker and aggregate progress is redrawn in place, otherwise a progress summary
+// line is written periodically.
+//
+// It is also an io.Writer so that log output can be routed through it and not clobber the
+// progress display.
+type progressRenderer struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	interval time.Duration
+	start    time.Time
+
+	workers map[int]*workerProgress
+	// total number of files, from the search hits
+	total int
+	// expected total bytes of all queued downloads
+	expected int64
+	// size of finished downloads
+	finished int64
+	// bytes actually transferred, used for throughput
+	transferred int64
+	queued      int
+	completed   int
+	failed      int
+
+	// number of progress lines currently drawn
+	lines int
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+var _ internal.ProgressTracker = (*progressRenderer)(nil)
+
+func newProgressRenderer(out io.Writer, tty bool) *progressRenderer {
+	interval := logProgressInterval
+	if tty {
+		interval = ttyProgressInterval
+	}
+	return &progressRenderer{
+		out:      out,
+		tty:      tty,
+		interval: interval,
+		start:    time.Now(),
+		workers:  map[int]*workerProgress{},
+		done:     make(chan struct{}),
+	}
+}
+
+// Run starts rendering until Stop is called
+func (p *progressRenderer) Run() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				p.mu.Lock()
+				p.clear()
+				p.mu.Unlock()
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.render()
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop rendering and remove any progress display
+func (p *progressRenderer) Stop() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+// SetTotal sets the total number of files expected to be downloaded
+func (p *progressRenderer) SetTotal(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = n
+}
+
+// Queued adds a request to the expected totals
+func (p *progressRenderer) Queued(req internal.DownloadRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queued++
+	p.expected += req.Size
+}
+
+func (p *progressRenderer) Started(worker int, req internal.DownloadRequest) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.workers[worker] = &workerProgress{name: filepath.Base(req.Dest), expected: req.Size}
+}
+
+func (p *progressRenderer) Wrote(worker int, n int, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transferred += int64(n)
+	if w, ok := p.workers[worker]; ok {
+		w.size = size
+	}
+}
+
+func (p *progressRenderer) Finished(worker int, zult internal.DownloadResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.workers, worker)
+	if zult.Err != nil {
+		p.failed++
+	} else {
+		p.completed++
+		p.finished += zult.Size
+	}
+}
+
+// Write writes log output above the progress display
+func (p *progressRenderer) Write(buf []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	return p.out.Write(buf)
+}
+
+// clear erases the progress lines. Must be called with the lock held.
+func (p *progressRenderer) clear() {
+	if !p.tty || p.lines == 0 {
+		return
+	}
+	fmt.Fprintf(p.out, "\x1b[%dA\x1b[J", p.lines)
+	p.lines = 0
+}
+
+func percent(size, expected int64) string {
+	if expected <= 0 {
+		return "?"
+	}
+	return fmt.Sprintf("%.0f%%", float64(size)/float64(expected)*100)
+}
+
+// summary is the aggregate progress line. Must be called with the lock held.
+func (p *progressRenderer) summary() string {
+	done := p.finished
+	for _, w := range p.workers {
+		done += w.size
+	}
+	elapsed := time.Since(p.start).Seconds()
+	var rate float64 // bytes/s
+	if elapsed > 0 {
+		rate = float64(p.transferred) / elapsed
+	}
+	// Requests are queued as search results are read so until everything is queued the
+	// expected size is estimated from the sizes of the requests queued so far
+	expected, approx := p.expected, ""
+	if p.queued > 0 && p.queued < p.total {
+		expected = p.expected * int64(p.total) / int64(p.queued)
+		approx = "~"
+	}
+	total := p.total
+	if p.queued > total {
+		total = p.queued
+	}
+	eta := "?"
+	if rate > 0 && expected > done {
+		eta = (time.Duration(float64(expected-done)/rate) * time.Second).Round(time.Second).String()
+	}
+	s := fmt.Sprintf("%d/%s%d files, %s of %s%s (%s), %.1f Mb/s, ETA %s",
+		p.completed, approx, total,
+		internal.ByteCountSI(done), approx, internal.ByteCountSI(expected), percent(done, expected),
+		rate*8/(1024*1024), eta)
+	if p.failed > 0 {
+		s += fmt.Sprintf(", %d failed", p.failed)
+	}
+	return s
+}
+
+// render draws progress. Must be called with the lock held.
+func (p *progressRenderer) render() {
+	if !p.tty {
+		fmt.Fprintf(p.out, "%s progress: %s\n", time.Now().Format("2006/01/02 15:04:05"), p.summary())
+		return
+	}
+	p.clear()
+	ids := []int{}
+	for id := range p.workers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	lines := []string{}
+	for _, id := range ids {
+		w := p.workers[id]
+		lines = append(lines, fmt.Sprintf("  [%d] %s %s / %s (%s)",
+			id, elipsis(w.name, 64), internal.ByteCountSI(w.size), internal.ByteCountSI(w.expected),
+			percent(w.size, w.expected)))
+	}
+	lines = append(lines, p.summary())
+	fmt.Fprintln(p.out, strings.Join(lines, "\n"))
+	p.lines = len(lines)
+}
+
+func elipsis(s string, maxLen int) string {
+	if len(s) < maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
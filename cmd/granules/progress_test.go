@@ -0,0 +1,67 @@
+package granules
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressRenderer(t *testing.T) {
+	t.Run("summary", func(t *testing.T) {
+		p := newProgressRenderer(&bytes.Buffer{}, false)
+		p.SetTotal(4)
+		p.Queued(internal.DownloadRequest{Dest: "/a", Size: 100})
+		p.Queued(internal.DownloadRequest{Dest: "/b", Size: 100})
+		p.Started(0, internal.DownloadRequest{Dest: "/a", Size: 100})
+		p.Wrote(0, 100, 100)
+		p.Finished(0, internal.DownloadResult{Size: 100})
+		p.Started(1, internal.DownloadRequest{Dest: "/b", Size: 100})
+		p.Wrote(1, 50, 50)
+
+		s := p.summary()
+		// only half queued so expected is estimated
+		require.True(t, strings.HasPrefix(s, "1/~4 files, 150 B of ~400 B (38%)"), s)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		p := newProgressRenderer(&bytes.Buffer{}, false)
+		p.SetTotal(1)
+		p.Queued(internal.DownloadRequest{Dest: "/a", Size: 100})
+		p.Started(0, internal.DownloadRequest{Dest: "/a", Size: 100})
+		p.Finished(0, internal.DownloadResult{Err: fmt.Errorf("boom")})
+
+		require.Contains(t, p.summary(), "1 failed")
+		require.Empty(t, p.workers)
+	})
+
+	t.Run("tty render and clear", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		p := newProgressRenderer(buf, true)
+		p.Started(0, internal.DownloadRequest{Dest: "/dir/file.nc", Size: 10})
+		p.render()
+		require.Equal(t, 2, p.lines)
+		require.Contains(t, buf.String(), "[0] file.nc")
+
+		buf.Reset()
+		_, err := p.Write([]byte("log message\n"))
+		require.NoError(t, err)
+		require.Equal(t, "\x1b[2A\x1b[Jlog message\n", buf.String())
+		require.Equal(t, 0, p.lines)
+	})
+
+	t.Run("non-tty does not clear", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		p := newProgressRenderer(buf, false)
+		p.render()
+		require.Contains(t, buf.String(), "progress: ")
+
+		buf.Reset()
+		_, err := p.Write([]byte("log message\n"))
+		require.NoError(t, err)
+		require.Equal(t, "log message\n", buf.String())
+	})
+}
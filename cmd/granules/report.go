@@ -0,0 +1,160 @@
+package granules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/bmflynn/cmrfetch/internal"
+)
+
+var reportFormats = []string{"json", "ndjson"}
+
+// reportError is the structured form of a download error
+type reportError struct {
+	Message    string `json:"message"`
+	Status     string `json:"status,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	URL        string `json:"url,omitempty"`
+}
+
+func newReportError(err error) *reportError {
+	if err == nil {
+		return nil
+	}
+	zult := &reportError{Message: err.Error()}
+	var failed *internal.FailedDownload
+	if errors.As(err, &failed) {
+		zult.Status = failed.Status
+		zult.StatusCode = failed.StatusCode
+		zult.RequestID = failed.RequestID
+		zult.URL = failed.URL
+	}
+	return zult
+}
+
+// reportEntry is the report for a single granule download
+type reportEntry struct {
+	// One of downloaded, skipped, or failed
+	Status string `json:"status"`
+	URL    string `json:"url"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	// Download duration in seconds
+	Duration float64 `json:"duration"`
+	Attempts int     `json:"attempts,omitempty"`
+	Checksum string  `json:"checksum,omitempty"`
+	// Why the download was skipped
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Why checksum verification was skipped
+	ChecksumVerificationSkipped string       `json:"checksum_verification_skipped,omitempty"`
+	Error                       *reportError `json:"error"`
+}
+
+func newReportEntry(zult internal.DownloadResult) reportEntry {
+	entry := reportEntry{
+		Status:                      "downloaded",
+		URL:                         zult.URL,
+		Path:                        zult.Path,
+		Size:                        zult.Size,
+		Duration:                    zult.Duration.Seconds(),
+		Attempts:                    zult.Attempts,
+		Checksum:                    zult.Checksum,
+		ChecksumVerificationSkipped: zult.ChecksumVerificationSkipped,
+		Error:                       newReportError(zult.Err),
+	}
+	if zult.Err != nil {
+		entry.Status = "failed"
+	}
+	return entry
+}
+
+// downloadReport writes a machine-readable report of download results. For ndjson entries
+// are written as they are added, otherwise all entries are written as a JSON array by Close.
+//
+// Safe for concurrent use.
+type downloadReport struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	ndjson  bool
+	entries []reportEntry
+	closed  bool
+}
+
+func newDownloadReport(w io.Writer, format string) (*downloadReport, error) {
+	if !arrayContains(reportFormats, format) {
+		return nil, fmt.Errorf("invalid download report format %q", format)
+	}
+	return &downloadReport{w: w, ndjson: format == "ndjson", entries: []reportEntry{}}, nil
+}
+
+// openDownloadReport opens a report writing to fpath, or stdout if fpath is "-"
+func openDownloadReport(fpath, format string) (*downloadReport, error) {
+	if fpath == "-" {
+		return newDownloadReport(os.Stdout, format)
+	}
+	f, err := os.Create(fpath)
+	if err != nil {
+		return nil, fmt.Errorf("creating download report: %w", err)
+	}
+	report, err := newDownloadReport(f, format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	report.closer = f
+	return report, nil
+}
+
+func (r *downloadReport) add(entry reportEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ndjson {
+		return json.NewEncoder(r.w).Encode(entry)
+	}
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+// Result adds a download result to the report
+func (r *downloadReport) Result(zult internal.DownloadResult) error {
+	return r.add(newReportEntry(zult))
+}
+
+// Skipped adds a download that was skipped to the report
+func (r *downloadReport) Skipped(req internal.DownloadRequest, reason string) error {
+	return r.add(reportEntry{
+		Status:     "skipped",
+		URL:        req.URL,
+		Path:       req.Dest,
+		SkipReason: reason,
+	})
+}
+
+// Close writes any remaining entries and closes the underlying file, if any. Calls after the
+// first do nothing.
+func (r *downloadReport) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	var err error
+	if !r.ndjson {
+		enc := json.NewEncoder(r.w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r.entries)
+	}
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package granules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadReport(t *testing.T) {
+	downloaded := internal.DownloadResult{
+		URL:      "https://host/a.nc",
+		Path:     "/data/a.nc",
+		Checksum: "xxx",
+		Duration: 1500 * time.Millisecond,
+		Size:     10,
+		Attempts: 1,
+	}
+	failed := internal.DownloadResult{
+		URL:      "https://host/b.nc",
+		Path:     "/data/b.nc",
+		Attempts: 3,
+		Err: &internal.FetchError{Err: &internal.FailedDownload{
+			RequestID:  "rid",
+			Status:     "503 Service Unavailable",
+			StatusCode: 503,
+			URL:        "https://host/b.nc",
+		}},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		report, err := newDownloadReport(buf, "json")
+		require.NoError(t, err)
+
+		require.NoError(t, report.Result(downloaded))
+		require.NoError(t, report.Skipped(internal.DownloadRequest{URL: "https://host/c.nc", Dest: "/data/c.nc"}, "exists by name"))
+		require.NoError(t, report.Result(failed))
+		require.Zero(t, buf.Len(), "expected nothing written until close")
+		require.NoError(t, report.Close())
+		n := buf.Len()
+		require.NoError(t, report.Close())
+		require.Equal(t, n, buf.Len(), "closing again should not write")
+
+		entries := []reportEntry{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entries))
+		require.Len(t, entries, 3)
+
+		require.Equal(t, "downloaded", entries[0].Status)
+		require.Equal(t, 1.5, entries[0].Duration)
+		require.Equal(t, "xxx", entries[0].Checksum)
+		require.Nil(t, entries[0].Error)
+
+		require.Equal(t, "skipped", entries[1].Status)
+		require.Equal(t, "exists by name", entries[1].SkipReason)
+
+		require.Equal(t, "failed", entries[2].Status)
+		require.Equal(t, 3, entries[2].Attempts)
+		require.NotNil(t, entries[2].Error)
+		require.Equal(t, "rid", entries[2].Error.RequestID)
+		require.Equal(t, 503, entries[2].Error.StatusCode)
+		require.Equal(t, "503 Service Unavailable", entries[2].Error.Status)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		report, err := newDownloadReport(buf, "ndjson")
+		require.NoError(t, err)
+
+		require.NoError(t, report.Result(downloaded))
+		require.NotZero(t, buf.Len(), "expected entry to be written immediately")
+		require.NoError(t, report.Result(failed))
+		require.NoError(t, report.Close())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		for _, line := range lines {
+			entry := reportEntry{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		}
+	})
+
+	t.Run("error without http status", func(t *testing.T) {
+		zult := newReportError(&internal.FetchError{Err: fmt.Errorf("connection reset")})
+		require.Equal(t, "fetching: connection reset", zult.Message)
+		require.Zero(t, zult.StatusCode)
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		_, err := newDownloadReport(&bytes.Buffer{}, "xml")
+		require.Error(t, err)
+	})
+}
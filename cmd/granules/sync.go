@@ -0,0 +1,338 @@
+package granules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/bmflynn/cmrfetch/internal/log"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultSyncInterval      = 10 * time.Minute
+	defaultSyncInitialWindow = 24 * time.Hour
+	syncStateName            = ".cmrfetch-sync.json"
+)
+
+var syncTimerange internal.TimeRangesValue = internal.NewTimeRangesValue()
+
+var SyncCmd = &cobra.Command{
+	Use:   "sync (--collection=COL|--nativeid=ID|--shortname=NAME) --download=DIR [flags]",
+	Short: "Continuously download new and revised granules",
+	Long: `
+Continuously download new and revised granules
+
+Sync polls CMR on an interval for granules matching the search filters that have
+been created or revised since the last poll and downloads them. Progress is
+tracked using a watermark, the revision date of the newest granule processed,
+that is saved per query in a state file so sync can be stopped and restarted
+without re-listing or re-downloading granules.
+
+The first time a query is synced only granules updated within --initial-window
+are downloaded. --timerange is not applied unless provided, so by default
+revised granules are downloaded regardless of their temporal extent.
+
+Revised granules replace any existing file of the same name unless
+--download-skip-checksum is used and the local checksum matches.
+
+If any downloads fail the watermark is not advanced past the failed granules so
+they are retried on the next poll.
+
+See the granules command for details on authentication.
+`,
+	Example: `
+  Keep a local archive of a near-real-time product up to date, checking every 5 minutes:
+
+    cmrfetch sync -s AERDT_L2_VIIRS_SNPP_NRT --download ./archive --interval 5m
+
+  Run a single poll, e.g., from cron:
+
+    cmrfetch sync -c C1964798938-LAADS --download ./archive --once
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+
+		if err := validateSearchFlags(flags); err != nil {
+			return err
+		}
+
+		verbose, err := flags.GetBool("verbose")
+		failOnError(err)
+		interval, err := flags.GetDuration("interval")
+		failOnError(err)
+		once, err := flags.GetBool("once")
+		failOnError(err)
+		initialWindow, err := flags.GetDuration("initial-window")
+		failOnError(err)
+		statePath, err := flags.GetString("state")
+		failOnError(err)
+
+		params, err := newParams(flags)
+		if err != nil {
+			return err
+		}
+		if flags.Changed("timerange") {
+			params.Timeranges(syncTimerange.Ranges...)
+		}
+
+		opts, err := newDownloadOptions(flags)
+		if err != nil {
+			return err
+		}
+		if opts.destdir == "" {
+			return fmt.Errorf("--download is required")
+		}
+		opts.yes = true
+		// only new and revised granules are downloaded so existing files must be replaced
+		opts.clobber = !opts.skipByChecksum
+
+		log.SetVerbose(verbose)
+
+		env, err := internal.CMREnvFromFlags(flags)
+		if err != nil {
+			return err
+		}
+		api := internal.NewCMRSearchAPI().WithURL(env.SearchURL())
+
+		if statePath == "" {
+			statePath = filepath.Join(opts.destdir, syncStateName)
+		}
+		state, err := internal.LoadSyncState(statePath)
+		if err != nil {
+			return err
+		}
+
+		// Identify the query before updated_since is set
+		query, err := params.Encode()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		for {
+			err := syncOnce(ctx, api, env, params, state, query, opts, initialWindow)
+			if once {
+				if err != nil {
+					log.Fatalf("failed! %s", err)
+				}
+				return nil
+			}
+			if err != nil {
+				log.Printf("sync failed! %s", err)
+			}
+			log.Debug("next poll in %s", interval)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+	},
+}
+
+func init() {
+	flags := SyncCmd.Flags()
+	flags.SortFlags = false
+
+	flags.BoolP("verbose", "v", false, "Verbose output")
+	flags.StringP("download", "d", "",
+		"Directory to download granules to. If the directory does not exist it will be created.")
+	flags.Duration("interval", defaultSyncInterval, "Time between polls")
+	flags.Bool("once", false, "Poll once and exit, rather than polling continuously.")
+	flags.Duration("initial-window", defaultSyncInitialWindow,
+		"Download granules updated within this duration the first time a query is synced.")
+	flags.String("state", "",
+		"Sync state file, which is shared by all queries. Defaults to "+syncStateName+" in the "+
+			"download directory.")
+	addDownloadFlags(flags)
+	addSearchFlags(flags, &syncTimerange)
+}
+
+// syncOnce downloads the granules for params that have been updated since the query's
+// watermark and updates the query state.
+func syncOnce(
+	ctx context.Context,
+	api *internal.CMRSearchAPI,
+	env internal.CMREnv,
+	params *internal.SearchGranuleParams,
+	state *internal.SyncState,
+	query string,
+	opts downloadOptions,
+	initialWindow time.Duration,
+) error {
+	key := internal.SyncQueryKey(env.SearchURL(), query)
+	prev, ok := state.Get(key)
+	if !ok {
+		prev = internal.SyncQueryState{
+			Query:     query,
+			Watermark: time.Now().Add(-initialWindow).UTC().Truncate(time.Second),
+		}
+	}
+	log.Printf("syncing granules updated since %s", prev.Watermark.Format(time.RFC3339))
+
+	// Stops the search if the download returns before all results are received
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	zult, err := api.SearchGranules(ctx, params.UpdatedSince(prev.Watermark))
+	if err != nil {
+		return err
+	}
+	log.Printf("%v results\n", zult.Hits())
+
+	destPath, err := newDestPath(opts)
+	if err != nil {
+		return err
+	}
+	poll := newSyncPoll(prev, destPath)
+	opts.onResult = poll.result
+	err = doDownload(ctx, api, env, zult.Filter(poll.keep), opts)
+
+	// Download failures are handled by the watermark, any other error means some granules
+	// may not have been processed
+	complete := zult.Err() == nil && (err == nil || poll.failures() > 0)
+	next := poll.next(complete)
+	if serr := state.Set(key, next); serr != nil {
+		return fmt.Errorf("saving sync state: %w", serr)
+	}
+	if next.Watermark.After(prev.Watermark) {
+		log.Printf("watermark advanced to %s", next.Watermark.Format(time.RFC3339))
+	}
+	return err
+}
+
+type syncGranule struct {
+	id      string
+	revised time.Time
+	failed  bool
+}
+
+// syncPoll tracks the granules processed by a single sync poll to determine the next
+// query state. Safe for concurrent use.
+type syncPoll struct {
+	mu       sync.Mutex
+	prev     internal.SyncQueryState
+	seen     map[string]bool
+	destPath destPathFunc
+	// all granules returned by the search, by download destination path
+	granules map[string]*syncGranule
+}
+
+func newSyncPoll(prev internal.SyncQueryState, destPath destPathFunc) *syncPoll {
+	seen := map[string]bool{}
+	for _, id := range prev.Seen {
+		seen[id] = true
+	}
+	return &syncPoll{prev: prev, seen: seen, destPath: destPath, granules: map[string]*syncGranule{}}
+}
+
+func syncGranuleID(gran internal.Granule) string {
+	return fmt.Sprintf("%s:%s/%s", gran.ConceptID, gran.RevisionID, gran.Name)
+}
+
+// keep records gran and returns whether it still needs to be downloaded
+func (p *syncPoll) keep(gran internal.Granule) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := syncGranuleID(gran)
+	revised, err := time.Parse(time.RFC3339, gran.RevisionDate)
+	if err != nil {
+		log.Debug("invalid revision date %q for %s", gran.RevisionDate, id)
+	}
+	if p.seen[id] {
+		p.granules[id] = &syncGranule{id: id, revised: revised}
+		log.Debug("skipping %s, already synced", gran.Name)
+		return false
+	}
+	dest, err := p.destPath(gran)
+	if err != nil {
+		// the download cannot be attempted, so it is failed now rather than by its result
+		p.granules[id] = &syncGranule{id: id, revised: revised, failed: true}
+		return true
+	}
+	p.granules[dest] = &syncGranule{id: id, revised: revised}
+	return true
+}
+
+// result records a download result
+func (p *syncPoll) result(zult internal.DownloadResult) {
+	if zult.Err == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if gran, ok := p.granules[zult.Path]; ok {
+		gran.failed = true
+	}
+}
+
+func (p *syncPoll) failures() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := 0
+	for _, gran := range p.granules {
+		if gran.failed {
+			count++
+		}
+	}
+	return count
+}
+
+// next returns the query state for the next poll. If the poll was not complete the watermark
+// is not changed because search results are not ordered by revision date.
+func (p *syncPoll) next(complete bool) internal.SyncQueryState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	next := internal.SyncQueryState{Query: p.prev.Query, Watermark: p.prev.Watermark}
+	if complete {
+		var newest, oldestFailed time.Time
+		for _, gran := range p.granules {
+			if gran.failed {
+				if oldestFailed.IsZero() || gran.revised.Before(oldestFailed) {
+					oldestFailed = gran.revised
+				}
+			} else if gran.revised.After(newest) {
+				newest = gran.revised
+			}
+		}
+		switch {
+		case !oldestFailed.IsZero():
+			next.Watermark = oldestFailed.Truncate(time.Second)
+		case !newest.IsZero():
+			next.Watermark = newest.Truncate(time.Second)
+		}
+		if next.Watermark.Before(p.prev.Watermark) {
+			next.Watermark = p.prev.Watermark
+		}
+	}
+
+	next.Seen = []string{}
+	listed := map[string]bool{}
+	for _, gran := range p.granules {
+		listed[gran.id] = true
+		if gran.failed || gran.revised.Before(next.Watermark) {
+			continue
+		}
+		next.Seen = append(next.Seen, gran.id)
+	}
+	if !complete {
+		// granules not returned by an incomplete poll may still be needed
+		for id := range p.seen {
+			if !listed[id] {
+				next.Seen = append(next.Seen, id)
+			}
+		}
+	}
+	sort.Strings(next.Seen)
+	return next
+}
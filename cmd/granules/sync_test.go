@@ -0,0 +1,97 @@
+package granules
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncPoll(t *testing.T) {
+	watermark := time.Date(2023, 4, 27, 0, 0, 0, 0, time.UTC)
+	gran := func(id, revised string) internal.Granule {
+		return internal.Granule{
+			Name:         id + ".nc",
+			ConceptID:    id,
+			RevisionID:   "1",
+			RevisionDate: revised,
+		}
+	}
+
+	t.Run("advances to newest", func(t *testing.T) {
+		poll := newSyncPoll(internal.SyncQueryState{Watermark: watermark}, flatDestPath("/data"))
+		require.True(t, poll.keep(gran("G1", "2023-04-27T01:00:00.500Z")))
+		require.True(t, poll.keep(gran("G2", "2023-04-27T02:00:00.500Z")))
+
+		next := poll.next(true)
+		require.Equal(t, time.Date(2023, 4, 27, 2, 0, 0, 0, time.UTC), next.Watermark)
+		require.Equal(t, []string{"G2:1/G2.nc"}, next.Seen)
+	})
+
+	t.Run("skips seen", func(t *testing.T) {
+		poll := newSyncPoll(internal.SyncQueryState{Watermark: watermark, Seen: []string{"G1:1/G1.nc"}}, flatDestPath("/data"))
+		require.False(t, poll.keep(gran("G1", "2023-04-27T00:00:00Z")))
+		// a new revision is not seen
+		revised := gran("G1", "2023-04-27T01:00:00Z")
+		revised.RevisionID = "2"
+		require.True(t, poll.keep(revised))
+	})
+
+	t.Run("failure holds watermark", func(t *testing.T) {
+		poll := newSyncPoll(internal.SyncQueryState{Watermark: watermark}, flatDestPath("/data"))
+		require.True(t, poll.keep(gran("G1", "2023-04-27T01:00:00Z")))
+		require.True(t, poll.keep(gran("G2", "2023-04-27T02:00:00Z")))
+		require.True(t, poll.keep(gran("G3", "2023-04-27T03:00:00Z")))
+		poll.result(internal.DownloadResult{Path: "/data/G2.nc", Err: fmt.Errorf("boom")})
+		poll.result(internal.DownloadResult{Path: "/data/G3.nc"})
+
+		require.Equal(t, 1, poll.failures())
+		next := poll.next(true)
+		require.Equal(t, time.Date(2023, 4, 27, 2, 0, 0, 0, time.UTC), next.Watermark)
+		require.Equal(t, []string{"G3:1/G3.nc"}, next.Seen)
+	})
+
+	t.Run("failure with renaming template", func(t *testing.T) {
+		destPath, err := templateDestPath("/data", "{{.ConceptID}}/x_{{.Name}}")
+		require.NoError(t, err)
+		poll := newSyncPoll(internal.SyncQueryState{Watermark: watermark}, destPath)
+		require.True(t, poll.keep(gran("G1", "2023-04-27T01:00:00Z")))
+		require.True(t, poll.keep(gran("G2", "2023-04-27T02:00:00Z")))
+		require.True(t, poll.keep(gran("G3", "2023-04-27T03:00:00Z")))
+		poll.result(internal.DownloadResult{Path: "/data/G2/x_G2.nc", Err: fmt.Errorf("boom")})
+		poll.result(internal.DownloadResult{Path: "/data/G3/x_G3.nc"})
+
+		require.Equal(t, 1, poll.failures())
+		next := poll.next(true)
+		require.Equal(t, time.Date(2023, 4, 27, 2, 0, 0, 0, time.UTC), next.Watermark)
+	})
+
+	t.Run("template error is a failure", func(t *testing.T) {
+		destPath, err := templateDestPath("/data", "../{{.Name}}")
+		require.NoError(t, err)
+		poll := newSyncPoll(internal.SyncQueryState{Watermark: watermark}, destPath)
+		require.True(t, poll.keep(gran("G1", "2023-04-27T01:00:00Z")))
+
+		require.Equal(t, 1, poll.failures())
+		next := poll.next(true)
+		require.Equal(t, time.Date(2023, 4, 27, 1, 0, 0, 0, time.UTC), next.Watermark)
+	})
+
+	t.Run("incomplete keeps watermark", func(t *testing.T) {
+		poll := newSyncPoll(internal.SyncQueryState{Watermark: watermark, Seen: []string{"G0:1/G0.nc"}}, flatDestPath("/data"))
+		require.True(t, poll.keep(gran("G1", "2023-04-27T01:00:00Z")))
+
+		next := poll.next(false)
+		require.Equal(t, watermark, next.Watermark)
+		require.Equal(t, []string{"G0:1/G0.nc", "G1:1/G1.nc"}, next.Seen)
+	})
+
+	t.Run("no results keeps state", func(t *testing.T) {
+		poll := newSyncPoll(internal.SyncQueryState{Watermark: watermark}, flatDestPath("/data"))
+		next := poll.next(true)
+		require.Equal(t, watermark, next.Watermark)
+		require.Empty(t, next.Seen)
+	})
+}
@@ -0,0 +1,106 @@
+package granules
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal"
+)
+
+// destPathFunc returns the absolute download destination for a granule
+type destPathFunc func(internal.Granule) (string, error)
+
+// flatDestPath writes all granules directly to destdir using the granule name
+func flatDestPath(destdir string) destPathFunc {
+	if !filepath.IsAbs(destdir) {
+		panic("destdir is not absolute")
+	}
+	return func(gran internal.Granule) (string, error) {
+		return filepath.Join(destdir, gran.Name), nil
+	}
+}
+
+// destTemplateData is the data available to a download template
+type destTemplateData struct {
+	internal.Granule
+	// Collection short name and version
+	ShortName string
+	Version   string
+	// Granule temporal range; nil if not available
+	Start *time.Time
+	End   *time.Time
+}
+
+func newDestTemplateData(gran internal.Granule) destTemplateData {
+	dat := destTemplateData{Granule: gran}
+	if idx := strings.LastIndex(gran.Collection, "/"); idx >= 0 {
+		dat.ShortName = gran.Collection[:idx]
+		dat.Version = gran.Collection[idx+1:]
+	}
+	parse := func(s string) *time.Time {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+	if len(gran.TimeRange) == 2 {
+		dat.Start = parse(gran.TimeRange[0])
+		dat.End = parse(gran.TimeRange[1])
+	}
+	return dat
+}
+
+var destTemplateFuncs = template.FuncMap{
+	// 3 digit day of year
+	"doy": func(t *time.Time) (string, error) {
+		if t == nil {
+			return "", fmt.Errorf("time not available")
+		}
+		return fmt.Sprintf("%03d", t.YearDay()), nil
+	},
+}
+
+// templateDestPath renders download destinations from a Go template over destTemplateData,
+// relative to destdir. If the template renders a directory, i.e., it ends with a /, the granule
+// name is appended. Destinations outside of destdir are rejected.
+func templateDestPath(destdir, text string) (destPathFunc, error) {
+	if !filepath.IsAbs(destdir) {
+		panic("destdir is not absolute")
+	}
+	tmpl, err := template.New("download").Option("missingkey=error").Funcs(destTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download template: %w", err)
+	}
+
+	render := func(gran internal.Granule) (string, error) {
+		buf := &strings.Builder{}
+		if err := tmpl.Execute(buf, newDestTemplateData(gran)); err != nil {
+			return "", fmt.Errorf("rendering download template: %w", err)
+		}
+		rel := buf.String()
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			rel += gran.Name
+		}
+		if filepath.IsAbs(rel) {
+			return "", fmt.Errorf("download template rendered absolute path %s", rel)
+		}
+		dest := filepath.Join(destdir, rel)
+		if r, err := filepath.Rel(destdir, dest); err != nil || r == "." || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("download template rendered path %s outside of %s", rel, destdir)
+		}
+		return dest, nil
+	}
+
+	// Execute with a sample granule so invalid field names are found before downloading
+	now := time.Now().UTC().Format(time.RFC3339)
+	sample := internal.Granule{Name: "sample.nc", Collection: "SHORTNAME/1", TimeRange: []string{now, now}}
+	if err := tmpl.Execute(io.Discard, newDestTemplateData(sample)); err != nil {
+		return nil, fmt.Errorf("invalid download template: %w", err)
+	}
+	return render, nil
+}
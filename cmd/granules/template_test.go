@@ -0,0 +1,63 @@
+package granules
+
+import (
+	"testing"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateDestPath(t *testing.T) {
+	gran := internal.Granule{
+		Name:         "AERDT_L2_VIIRS_SNPP.A2023117.1654.011.nrt.nc",
+		Collection:   "AERDT_L2_VIIRS_SNPP_NRT/1.1",
+		NativeID:     "ASIPS:AERDT_L2_VIIRS_SNPP_NRT:1682614440",
+		DayNightFlag: "Day",
+		TimeRange:    []string{"2023-04-27T16:54:00.000000Z", "2023-04-27T16:59:59.000000Z"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"archive layout", "{{.ShortName}}/{{.Version}}/{{.Start.Year}}/{{doy .Start}}/{{.Name}}",
+			"/data/AERDT_L2_VIIRS_SNPP_NRT/1.1/2023/117/AERDT_L2_VIIRS_SNPP.A2023117.1654.011.nrt.nc"},
+		{"directory appends name", `{{.DayNightFlag}}/{{.Start.Format "2006-01-02"}}/`,
+			"/data/Day/2023-04-27/AERDT_L2_VIIRS_SNPP.A2023117.1654.011.nrt.nc"},
+		{"contained parent refs", "a/../{{.Name}}", "/data/AERDT_L2_VIIRS_SNPP.A2023117.1654.011.nrt.nc"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			destPath, err := templateDestPath("/data", test.template)
+			require.NoError(t, err)
+			dest, err := destPath(gran)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, dest)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, text := range []string{"{{.Name", "{{.NotAField}}", "{{doy .Name}}"} {
+			_, err := templateDestPath("/data", text)
+			require.Error(t, err, text)
+		}
+	})
+
+	t.Run("traversal rejected", func(t *testing.T) {
+		for _, text := range []string{"../{{.Name}}", "a/../../{{.Name}}", "/etc/{{.Name}}", "{{.Name}}/.."} {
+			destPath, err := templateDestPath("/data", text)
+			if err == nil {
+				_, err = destPath(gran)
+			}
+			require.Error(t, err, text)
+		}
+	})
+
+	t.Run("missing time is err", func(t *testing.T) {
+		destPath, err := templateDestPath("/data", "{{doy .Start}}/")
+		require.NoError(t, err)
+		_, err = destPath(internal.Granule{Name: "x.nc"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,171 @@
+package granules
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bmflynn/cmrfetch/internal"
+)
+
+// Output writers for handing downloads off to other transfer tools
+
+// metalinkHashTypes maps checksum algorithms to Metalink hash types
+var metalinkHashTypes = map[string]string{
+	"MD5":      "md5",
+	"SHA-1":    "sha-1",
+	"SHA-256":  "sha-256",
+	"SHA-384":  "sha-384",
+	"SHA-512":  "sha-512",
+	"Adler-32": "adler32",
+}
+
+// checksumCommands maps checksum algorithms to coreutils checksum commands
+var checksumCommands = map[string]string{
+	"MD5":     "md5sum",
+	"SHA-1":   "sha1sum",
+	"SHA-256": "sha256sum",
+	"SHA-384": "sha384sum",
+	"SHA-512": "sha512sum",
+}
+
+// lookupAlg returns the value for the granule's checksum alg, if it has a checksum
+func lookupAlg(m map[string]string, gran internal.Granule) (string, bool) {
+	alg, ok := internal.CanonicalChecksumAlg(gran.ChecksumAlg)
+	if !ok || gran.Checksum == "" {
+		return "", false
+	}
+	val, ok := m[alg]
+	return val, ok
+}
+
+type metalinkHash struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type metalinkURL struct {
+	Priority int    `xml:"priority,attr"`
+	URL      string `xml:",chardata"`
+}
+
+type metalinkFile struct {
+	XMLName xml.Name      `xml:"file"`
+	Name    string        `xml:"name,attr"`
+	Size    int64         `xml:"size,omitempty"`
+	Hash    *metalinkHash `xml:"hash,omitempty"`
+	URLs    []metalinkURL `xml:"url"`
+}
+
+// metalinkWriter writes a Metalink 4 (RFC 5854) document. Direct access urls are included as
+// lower priority mirrors.
+func metalinkWriter(zult internal.GranuleResult, w io.Writer, _ []string) error {
+	_, err := fmt.Fprintf(w, "%s<metalink xmlns=\"urn:ietf:params:xml:ns:metalink\">\n"+
+		"  <generator>cmrfetch/%s</generator>\n", xml.Header, internal.Version)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("  ", "  ")
+	for gran := range zult.Ch {
+		file := metalinkFile{Name: gran.Name}
+		if gran.SizeExact {
+			file.Size = gran.SizeBytes
+		}
+		if typ, ok := lookupAlg(metalinkHashTypes, gran); ok {
+			file.Hash = &metalinkHash{Type: typ, Value: strings.ToLower(gran.Checksum)}
+		}
+		for _, url := range []string{gran.GetDataURL, gran.GetDataDAURL} {
+			if url != "" {
+				file.URLs = append(file.URLs, metalinkURL{Priority: len(file.URLs) + 1, URL: url})
+			}
+		}
+		if err := enc.Encode(file); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "\n</metalink>\n"); err != nil {
+		return err
+	}
+	return zult.Err()
+}
+
+// aria2Writer writes an aria2c input file, see aria2c --input-file. Direct access urls are not
+// included because aria2 does not support s3 urls.
+func aria2Writer(zult internal.GranuleResult, w io.Writer, _ []string) error {
+	for gran := range zult.Ch {
+		if gran.GetDataURL == "" {
+			continue
+		}
+		s := fmt.Sprintf("%s\n  out=%s\n", gran.GetDataURL, gran.Name)
+		if typ, ok := lookupAlg(metalinkHashTypes, gran); ok {
+			s += fmt.Sprintf("  checksum=%s=%s\n", typ, strings.ToLower(gran.Checksum))
+		}
+		if _, err := io.WriteString(w, s); err != nil {
+			return err
+		}
+	}
+	return zult.Err()
+}
+
+// shellComment makes s safe to use in a shell comment
+func shellComment(s string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(s)
+}
+
+// shellQuote quotes s for use as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+const scriptHeader = `#!/bin/sh
+# Generated by cmrfetch %s
+#
+# NASA Earthdata Login credentials are read from ~/.netrc and session cookies are
+# stored in $COOKIES.
+set -e
+COOKIES=${COOKIES:-$HOME/.cmrfetch_cookies}
+touch "$COOKIES"
+`
+
+func newScriptWriter(command func(url, name string) string) outputWriter {
+	return func(zult internal.GranuleResult, w io.Writer, _ []string) error {
+		if _, err := fmt.Fprintf(w, scriptHeader, internal.Version); err != nil {
+			return err
+		}
+		for gran := range zult.Ch {
+			if gran.GetDataURL == "" {
+				continue
+			}
+			s := "\n"
+			if gran.Size != "" {
+				s += fmt.Sprintf("# %s, %s\n", shellComment(gran.Name), shellComment(gran.Size))
+			}
+			if gran.GetDataDAURL != "" {
+				s += fmt.Sprintf("# direct access: %s\n", shellComment(gran.GetDataDAURL))
+			}
+			s += command(gran.GetDataURL, gran.Name) + "\n"
+			if cmd, ok := lookupAlg(checksumCommands, gran); ok {
+				s += fmt.Sprintf("echo %s | %s -c -\n",
+					shellQuote(strings.ToLower(gran.Checksum)+"  "+gran.Name), cmd)
+			}
+			if _, err := io.WriteString(w, s); err != nil {
+				return err
+			}
+		}
+		return zult.Err()
+	}
+}
+
+// wgetWriter writes a shell script that downloads granules using wget
+var wgetWriter = newScriptWriter(func(url, name string) string {
+	return fmt.Sprintf("wget --no-verbose --continue --auth-no-challenge=on --keep-session-cookies "+
+		"--load-cookies \"$COOKIES\" --save-cookies \"$COOKIES\" -O %s %s", shellQuote(name), shellQuote(url))
+})
+
+// curlWriter writes a shell script that downloads granules using curl
+var curlWriter = newScriptWriter(func(url, name string) string {
+	return fmt.Sprintf("curl --fail --location --netrc-optional --cookie \"$COOKIES\" --cookie-jar \"$COOKIES\" "+
+		"--continue-at - --output %s %s", shellQuote(name), shellQuote(url))
+})
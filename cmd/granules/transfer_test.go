@@ -0,0 +1,90 @@
+package granules
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestResult(granules ...internal.Granule) internal.GranuleResult {
+	zult := internal.GranuleResult{Ch: make(chan internal.Granule, len(granules))}
+	for _, gran := range granules {
+		zult.Ch <- gran
+	}
+	close(zult.Ch)
+	return zult
+}
+
+var transferGranules = []internal.Granule{
+	{
+		Name:         "a.nc",
+		SizeBytes:    1234,
+		SizeExact:    true,
+		Size:         "1.2 kB",
+		Checksum:     "ABCDEF",
+		ChecksumAlg:  "SHA-256",
+		GetDataURL:   "https://host/a.nc",
+		GetDataDAURL: "s3://bucket/a.nc",
+	},
+	{
+		Name:        "b'c.nc",
+		Checksum:    "123",
+		ChecksumAlg: "Fletcher-32",
+		GetDataURL:  "https://host/b'c.nc",
+	},
+}
+
+func TestMetalinkWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, metalinkWriter(newTestResult(transferGranules...), buf, nil))
+
+	doc := struct {
+		XMLName xml.Name       `xml:"urn:ietf:params:xml:ns:metalink metalink"`
+		Files   []metalinkFile `xml:"file"`
+	}{}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc), buf.String())
+	require.Len(t, doc.Files, 2)
+
+	file := doc.Files[0]
+	require.Equal(t, "a.nc", file.Name)
+	require.Equal(t, int64(1234), file.Size)
+	require.Equal(t, &metalinkHash{Type: "sha-256", Value: "abcdef"}, file.Hash)
+	require.Equal(t, []metalinkURL{{1, "https://host/a.nc"}, {2, "s3://bucket/a.nc"}}, file.URLs)
+
+	require.Nil(t, doc.Files[1].Hash, "fletcher is not a metalink hash type")
+	require.Zero(t, doc.Files[1].Size)
+}
+
+func TestAria2Writer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, aria2Writer(newTestResult(transferGranules...), buf, nil))
+	require.Equal(t, "https://host/a.nc\n  out=a.nc\n  checksum=sha-256=abcdef\n"+
+		"https://host/b'c.nc\n  out=b'c.nc\n", buf.String())
+}
+
+func TestScriptWriters(t *testing.T) {
+	for name, writer := range map[string]outputWriter{"wget": wgetWriter, "curl": curlWriter} {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			require.NoError(t, writer(newTestResult(transferGranules...), buf, nil))
+			script := buf.String()
+
+			require.True(t, strings.HasPrefix(script, "#!/bin/sh\n"))
+			require.Contains(t, script, name+" ")
+			require.Contains(t, script, "# direct access: s3://bucket/a.nc\n")
+			require.Contains(t, script, "echo 'abcdef  a.nc' | sha256sum -c -\n")
+			require.Contains(t, script, `'b'\''c.nc'`, "names must be shell quoted")
+			require.Equal(t, 1, strings.Count(script, " -c -"), "only supported checksums are verified")
+		})
+	}
+}
+
+func Test_shellQuote(t *testing.T) {
+	require.Equal(t, `'a b'`, shellQuote("a b"))
+	require.Equal(t, `'a'\''b'`, shellQuote("a'b"))
+	require.Equal(t, `'$HOME'`, shellQuote("$HOME"))
+}
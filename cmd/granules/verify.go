@@ -0,0 +1,435 @@
+package granules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/bmflynn/cmrfetch/internal/log"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+const (
+	verifyOK         = "ok"
+	verifyUnverified = "unverified"
+	verifyMissing    = "missing"
+	verifyCorrupt    = "corrupt"
+	verifyOutdated   = "outdated"
+	verifyExtra      = "extra"
+)
+
+var verifyTimerange internal.TimeRangesValue = internal.NewTimeRangesValue()
+
+var VerifyCmd = &cobra.Command{
+	Use:   "verify (--collection=COL|--nativeid=ID|--shortname=NAME) --download=DIR [flags]",
+	Short: "Verify local granules against CMR metadata",
+	Long: `
+Verify local granules against CMR metadata
+
+Searches for granules using the search filters and checks each against the
+matching local file in the download directory, which is determined the same way
+as when downloading, i.e., using --download-template if provided.
+
+Each granule file is reported as one of:
+
+  ok          the local checksum, or exact size, matches the CMR metadata
+  unverified  the local file exists but there is no checksum or exact size to verify
+  missing     there is no local file
+  corrupt     the local checksum or size does not match the CMR metadata
+  outdated    the manifest, see --manifest, has a different revision than CMR
+
+--timerange is not applied unless provided, so by default all granules matching
+the other filters are verified.
+
+Local files that do not match any granule are reported as extra. Hidden files,
+such as partial downloads and sync state, are ignored.
+
+Use --fetch to download missing, corrupt, and outdated granules again. The exit
+code is non-zero if any granules are missing, corrupt, or outdated and were not
+successfully fetched.
+`,
+	Example: `
+  Verify the last week of granules in an archive and re-fetch any bad ones:
+
+    cmrfetch verify -c C1964798938-LAADS -t 2023-04-01,2023-04-08 --download ./archive --fetch
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+
+		if err := validateSearchFlags(flags); err != nil {
+			return err
+		}
+
+		verbose, err := flags.GetBool("verbose")
+		failOnError(err)
+		fetch, err := flags.GetBool("fetch")
+		failOnError(err)
+		output, err := flags.GetString("output")
+		failOnError(err)
+		if output != "short" && output != "json" {
+			return fmt.Errorf("--output must be one of short, json")
+		}
+
+		params, err := newParams(flags)
+		if err != nil {
+			return err
+		}
+		if flags.Changed("timerange") {
+			params.Timeranges(verifyTimerange.Ranges...)
+		}
+
+		opts, err := newDownloadOptions(flags)
+		if err != nil {
+			return err
+		}
+		if opts.destdir == "" {
+			return fmt.Errorf("--download is required")
+		}
+
+		log.SetVerbose(verbose)
+
+		env, err := internal.CMREnvFromFlags(flags)
+		if err != nil {
+			return err
+		}
+		api := internal.NewCMRSearchAPI().WithURL(env.SearchURL())
+
+		// Stops the search if verification returns before all results are received
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ok, err := doVerify(ctx, api, env, params, opts, fetch, output)
+		if err != nil {
+			log.Fatalf("failed! %s", err)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return nil
+	},
+}
+
+func init() {
+	flags := VerifyCmd.Flags()
+	flags.SortFlags = false
+
+	flags.BoolP("verbose", "v", false, "Verbose output")
+	flags.StringP("download", "d", "",
+		"Directory containing the granules to verify, and where granules are fetched to with --fetch.")
+	flags.Bool("fetch", false, "Download missing, corrupt, and outdated granules again.")
+	flags.StringP("output", "o", "short", "Output format. One of short or json.")
+	addDownloadFlags(flags)
+	addSearchFlags(flags, &verifyTimerange)
+}
+
+type verifyResult struct {
+	Status     string `json:"status"`
+	Path       string `json:"path"`
+	Name       string `json:"name,omitempty"`
+	ConceptID  string `json:"concept_id,omitempty"`
+	RevisionID string `json:"revision_id,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	// Set if the granule was fetched again, for --fetch
+	Fetched bool `json:"fetched,omitempty"`
+}
+
+func (r verifyResult) bad() bool {
+	return r.Status == verifyMissing || r.Status == verifyCorrupt || r.Status == verifyOutdated
+}
+
+// verifier checks granules against local files. Safe for concurrent use.
+type verifier struct {
+	destPath destPathFunc
+	// revision ids from the manifest by <concept-id>/<name>, if using a manifest
+	revisions map[string]string
+	checksum  func(alg, path string) (string, error)
+
+	mu      sync.Mutex
+	results map[string]*verifyResult
+}
+
+func newVerifier(destPath destPathFunc, revisions map[string]string) *verifier {
+	return &verifier{
+		destPath:  destPath,
+		revisions: revisions,
+		checksum:  internal.Checksum,
+		results:   map[string]*verifyResult{},
+	}
+}
+
+// verify checks gran against its local file
+func (v *verifier) verify(gran internal.Granule) verifyResult {
+	zult := verifyResult{
+		Name:       gran.Name,
+		ConceptID:  gran.ConceptID,
+		RevisionID: gran.RevisionID,
+	}
+	dest, err := v.destPath(gran)
+	if err != nil {
+		zult.Status = verifyUnverified
+		zult.Reason = err.Error()
+		return zult
+	}
+	zult.Path = dest
+
+	fi, err := os.Stat(dest)
+	if err != nil {
+		zult.Status = verifyMissing
+		if !errors.Is(err, fs.ErrNotExist) {
+			zult.Reason = err.Error()
+		}
+		return zult
+	}
+
+	if rev, ok := v.revisions[gran.ConceptID+"/"+gran.Name]; ok && rev != gran.RevisionID {
+		zult.Status = verifyOutdated
+		zult.Reason = fmt.Sprintf("local revision %s, CMR revision %s", rev, gran.RevisionID)
+		return zult
+	}
+
+	verified := false
+	if gran.SizeExact {
+		if fi.Size() != gran.SizeBytes {
+			zult.Status = verifyCorrupt
+			zult.Reason = fmt.Sprintf("size %d, expected %d", fi.Size(), gran.SizeBytes)
+			return zult
+		}
+		verified = true
+	}
+
+	switch {
+	case gran.Checksum == "":
+		zult.Reason = "no checksum available"
+	case !internal.ChecksumAlgSupported(gran.ChecksumAlg):
+		zult.Reason = fmt.Sprintf("checksum alg %s not supported", gran.ChecksumAlg)
+	default:
+		checksum, err := v.checksum(gran.ChecksumAlg, dest)
+		if err != nil {
+			zult.Status = verifyUnverified
+			zult.Reason = fmt.Sprintf("checksum failed: %s", err)
+			return zult
+		}
+		if !internal.ChecksumsEqual(gran.ChecksumAlg, checksum, gran.Checksum) {
+			zult.Status = verifyCorrupt
+			zult.Reason = fmt.Sprintf("got checksum %s, expected %s", checksum, gran.Checksum)
+			return zult
+		}
+		zult.Reason = ""
+		verified = true
+	}
+
+	zult.Status = verifyOK
+	if !verified {
+		zult.Status = verifyUnverified
+	}
+	return zult
+}
+
+// keep records the verification result for gran and returns whether it needs to be fetched
+func (v *verifier) keep(gran internal.Granule) bool {
+	zult := v.verify(gran)
+	log.Debug("%s %s %s", zult.Status, gran.Name, zult.Reason)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key := zult.Path
+	if key == "" {
+		key = gran.ConceptID + "/" + gran.Name
+	}
+	v.results[key] = &zult
+	return zult.bad()
+}
+
+// fetched records a download result for a granule that was fetched again
+func (v *verifier) fetched(dl internal.DownloadResult) {
+	if dl.Err != nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if zult, ok := v.results[dl.Path]; ok {
+		zult.Fetched = true
+	}
+}
+
+// extra returns results for all non-hidden files in destdir that were not verified, excluding
+// the ignored paths.
+func (v *verifier) extra(destdir string, ignore ...string) ([]verifyResult, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	ignored := map[string]bool{}
+	for _, path := range ignore {
+		if path == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			ignored[abs] = true
+		}
+	}
+	zults := []verifyResult{}
+	err := filepath.WalkDir(destdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != destdir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || ignored[path] {
+			return nil
+		}
+		if _, ok := v.results[path]; !ok {
+			zults = append(zults, verifyResult{Status: verifyExtra, Path: path, Name: d.Name()})
+		}
+		return nil
+	})
+	return zults, err
+}
+
+// sorted returns all results ordered by path
+func (v *verifier) sorted() []verifyResult {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	zults := []verifyResult{}
+	for _, zult := range v.results {
+		zults = append(zults, *zult)
+	}
+	sort.Slice(zults, func(i, j int) bool { return zults[i].Path < zults[j].Path })
+	return zults
+}
+
+func manifestRevisions(fpath string) (map[string]string, error) {
+	revisions := map[string]string{}
+	if fpath == "" || !internal.Exists(fpath) {
+		return revisions, nil
+	}
+	manifest, err := internal.OpenManifest(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer manifest.Close()
+	err = manifest.Each(func(rec internal.ManifestRecord) error {
+		revisions[rec.ConceptID+"/"+rec.Name] = rec.RevisionID
+		return nil
+	})
+	return revisions, err
+}
+
+// doVerify verifies granules and writes the results, returning false if any granules are
+// missing, corrupt or outdated and were not fetched.
+func doVerify(
+	ctx context.Context,
+	api *internal.CMRSearchAPI,
+	env internal.CMREnv,
+	params *internal.SearchGranuleParams,
+	opts downloadOptions,
+	fetch bool,
+	output string,
+) (bool, error) {
+	destdir, err := filepath.Abs(opts.destdir)
+	if err != nil {
+		return false, fmt.Errorf("getting absolute path for %s", opts.destdir)
+	}
+	if !internal.IsDir(destdir) {
+		return false, fmt.Errorf("%s is not a directory", destdir)
+	}
+	destPath := flatDestPath(destdir)
+	if opts.template != "" {
+		destPath, err = templateDestPath(destdir, opts.template)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Read revisions up front; the manifest is opened again to record fetched granules
+	revisions, err := manifestRevisions(opts.manifestPath)
+	if err != nil {
+		return false, err
+	}
+	v := newVerifier(destPath, revisions)
+
+	zult, err := api.SearchGranules(ctx, params)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("%v results\n", zult.Hits())
+
+	bad := zult.Filter(v.keep)
+	if fetch {
+		opts.yes = true
+		opts.clobber = true
+		// the total number of granules to fetch is not known ahead of time
+		opts.progress = false
+		opts.onResult = v.fetched
+		if err := doDownload(ctx, api, env, bad, opts); err != nil {
+			log.Printf("fetch failed! %s", err)
+		}
+	} else {
+		for range bad.Ch {
+		}
+	}
+	if err := zult.Err(); err != nil {
+		return false, err
+	}
+
+	zults := v.sorted()
+	extra, err := v.extra(destdir, opts.manifestPath, opts.reportPath)
+	if err != nil {
+		return false, fmt.Errorf("finding extra files: %w", err)
+	}
+	zults = append(zults, extra...)
+
+	if err := writeVerifyResults(os.Stdout, zults, output); err != nil {
+		return false, err
+	}
+
+	counts := map[string]int{}
+	ok := true
+	for _, zult := range zults {
+		counts[zult.Status]++
+		if zult.bad() && !zult.Fetched {
+			ok = false
+		}
+	}
+	summary := []string{}
+	for _, status := range []string{verifyOK, verifyUnverified, verifyMissing, verifyCorrupt, verifyOutdated, verifyExtra} {
+		summary = append(summary, fmt.Sprintf("%s=%d", status, counts[status]))
+	}
+	log.Printf("verified %s", strings.Join(summary, " "))
+	return ok, nil
+}
+
+func writeVerifyResults(w io.Writer, zults []verifyResult, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		for _, zult := range zults {
+			if err := enc.Encode(zult); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"status", "path", "reason"})
+	for _, zult := range zults {
+		status := zult.Status
+		if zult.Fetched {
+			status += " (fetched)"
+		}
+		t.AppendRow(table.Row{status, zult.Path, zult.Reason})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,59 @@
+package granules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier(t *testing.T) {
+	destdir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(destdir, name), []byte(content), 0o644))
+	}
+	write("ok.nc", "")
+	write("corrupt.nc", "xxx")
+	write("short.nc", "xxx")
+	write("nochecksum.nc", "")
+	write("outdated.nc", "")
+	write("extra.nc", "")
+	write("manifest.db", "")
+	write(".ok.nc.partial", "")
+
+	emptyMD5 := "d41d8cd98f00b204e9800998ecf8427e"
+	v := newVerifier(flatDestPath(destdir), map[string]string{"G5/outdated.nc": "1"})
+
+	tests := []struct {
+		gran     internal.Granule
+		expected string
+	}{
+		{internal.Granule{Name: "ok.nc", Checksum: emptyMD5, ChecksumAlg: "MD5"}, verifyOK},
+		{internal.Granule{Name: "corrupt.nc", Checksum: emptyMD5, ChecksumAlg: "MD5"}, verifyCorrupt},
+		{internal.Granule{Name: "short.nc", SizeBytes: 10, SizeExact: true}, verifyCorrupt},
+		{internal.Granule{Name: "nochecksum.nc"}, verifyUnverified},
+		{internal.Granule{Name: "missing.nc", Checksum: emptyMD5, ChecksumAlg: "MD5"}, verifyMissing},
+		{internal.Granule{Name: "outdated.nc", ConceptID: "G5", RevisionID: "2"}, verifyOutdated},
+	}
+	for _, test := range tests {
+		zult := v.verify(test.gran)
+		require.Equal(t, test.expected, zult.Status, test.gran.Name)
+		require.Equal(t, test.expected != verifyOK && test.expected != verifyUnverified, v.keep(test.gran))
+	}
+
+	v.fetched(internal.DownloadResult{Path: filepath.Join(destdir, "missing.nc")})
+
+	zults := v.sorted()
+	require.Len(t, zults, len(tests))
+	for _, zult := range zults {
+		require.Equal(t, zult.Name == "missing.nc", zult.Fetched, zult.Name)
+	}
+
+	extra, err := v.extra(destdir, filepath.Join(destdir, "manifest.db"))
+	require.NoError(t, err)
+	require.Len(t, extra, 1)
+	require.Equal(t, verifyExtra, extra[0].Status)
+	require.Equal(t, "extra.nc", extra[0].Name)
+}
@@ -12,8 +12,14 @@ import (
 
 type outputWriter func(internal.GranuleResult, io.Writer, []string) error
 
-func shortWriter(zult internal.GranuleResult, w io.Writer, _ []string) error {
+func shortWriter(zult internal.GranuleResult, w io.Writer, extra []string) error {
 	fields := []string{"name", "size", "native_id", "concept_id", "revision_id"}
+	// other fields are ignored, but attributes are included so filtered values are visible
+	for _, name := range extra {
+		if strings.HasPrefix(name, internal.AttributeFieldPrefix) {
+			fields = append(fields, name)
+		}
+	}
 	t := table.NewWriter()
 	t.SetOutputMirror(w)
 	t.SetStyle(table.StyleLight)
@@ -120,5 +126,10 @@ func granuleToMap(gran internal.Granule, fields []string) map[string]any {
 			delete(mapDat, name)
 		}
 	}
+	for _, name := range fields {
+		if attr, ok := strings.CutPrefix(name, internal.AttributeFieldPrefix); ok {
+			mapDat[name] = strings.Join(gran.Attributes[attr], ",")
+		}
+	}
 	return mapDat
 }
@@ -0,0 +1,24 @@
+package granules
+
+import (
+	"testing"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGranuleToMap(t *testing.T) {
+	gran := internal.Granule{
+		Name:       "a.nc",
+		Size:       "1 MB",
+		Attributes: map[string][]string{"H": {"8"}, "MULTI": {"a", "b"}},
+	}
+
+	dat := granuleToMap(gran, []string{"name", "attribute.H", "attribute.MULTI", "attribute.MISSING"})
+	require.Equal(t, map[string]any{
+		"name":              "a.nc",
+		"attribute.H":       "8",
+		"attribute.MULTI":   "a,b",
+		"attribute.MISSING": "",
+	}, dat)
+}
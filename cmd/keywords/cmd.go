@@ -13,6 +13,7 @@ import (
 func init() {
 	flags := Cmd.Flags()
 	flags.BoolP("verbose", "v", false, "Verbose output")
+	internal.AddCacheFlags(flags, internal.DefaultResponseCacheTTL)
 }
 
 func failOnError(err error) {
@@ -49,7 +50,15 @@ correct value.
 
 		log.SetVerbose(verbose)
 
-		api := internal.NewCMRSearchAPI()
+		env, err := internal.CMREnvFromFlags(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		cache, err := internal.ResponseCacheFromFlags(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		api := internal.NewCMRSearchAPI().WithURL(env.SearchURL()).WithCache(cache)
 
 		zult, err := api.SearchFacets(context.Background(), args[0], nil)
 		if err != nil {
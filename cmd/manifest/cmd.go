@@ -0,0 +1,177 @@
+package manifest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal"
+	"github.com/bmflynn/cmrfetch/internal/log"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+)
+
+var csvFields = []string{
+	"concept_id", "revision_id", "name", "collection", "url", "path", "size", "checksum",
+	"checksum_alg", "fetched_at",
+}
+
+func init() {
+	flags := Cmd.Flags()
+	flags.BoolP("verbose", "v", false, "Verbose output")
+	flags.StringSliceP("collection", "c", nil, "Only include records for these collections, as <shortname>/<version>")
+	flags.StringP("output", "o", "short", "Output format. One of short, json, or csv.")
+}
+
+func failOnError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+var Cmd = &cobra.Command{
+	Use:   "manifest <path>",
+	Args:  cobra.ExactArgs(1),
+	Short: "List or export the records in a download manifest",
+	Long: `
+List or export the records in a download manifest
+
+A manifest is created by using --manifest with the granules or sync commands and
+records the granule concept id, revision id, destination path, size, checksum, and
+fetch time of every downloaded granule file.
+`,
+	Example: `
+  Export all records as CSV:
+
+    cmrfetch manifest ./archive/manifest.db -o csv > manifest.csv
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		verbose, err := flags.GetBool("verbose")
+		failOnError(err)
+		output, err := flags.GetString("output")
+		failOnError(err)
+		collections, err := flags.GetStringSlice("collection")
+		failOnError(err)
+
+		log.SetVerbose(verbose)
+
+		var writer recordWriter
+		switch output {
+		case "short":
+			writer = newTableWriter(os.Stdout)
+		case "json":
+			writer = newJSONWriter(os.Stdout)
+		case "csv":
+			writer = newCSVWriter(os.Stdout)
+		default:
+			return fmt.Errorf("--output must be one of short, json, csv")
+		}
+
+		if !internal.Exists(args[0]) {
+			return fmt.Errorf("manifest %s does not exist", args[0])
+		}
+		manifest, err := internal.OpenManifest(args[0])
+		if err != nil {
+			log.Fatalf("failed! %s", err)
+		}
+		defer manifest.Close()
+
+		want := map[string]bool{}
+		for _, name := range collections {
+			want[name] = true
+		}
+		err = manifest.Each(func(rec internal.ManifestRecord) error {
+			if len(want) > 0 && !want[rec.Collection] {
+				return nil
+			}
+			return writer.Write(rec)
+		})
+		if err == nil {
+			err = writer.Flush()
+		}
+		if err != nil {
+			log.Fatalf("failed! %s", err)
+		}
+		return nil
+	},
+}
+
+type recordWriter interface {
+	Write(internal.ManifestRecord) error
+	Flush() error
+}
+
+type tableWriter struct {
+	t table.Writer
+}
+
+func newTableWriter(w io.Writer) *tableWriter {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"name", "concept_id", "revision_id", "size", "fetched_at", "path"})
+	return &tableWriter{t: t}
+}
+
+func (w *tableWriter) Write(rec internal.ManifestRecord) error {
+	w.t.AppendRow(table.Row{
+		rec.Name, rec.ConceptID, rec.RevisionID, internal.ByteCountSI(rec.Size),
+		rec.FetchedAt.Format(time.RFC3339), rec.Path,
+	})
+	return nil
+}
+
+func (w *tableWriter) Flush() error {
+	w.t.Render()
+	return nil
+}
+
+type jsonWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONWriter(w io.Writer) *jsonWriter {
+	return &jsonWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *jsonWriter) Write(rec internal.ManifestRecord) error { return w.enc.Encode(rec) }
+
+func (w *jsonWriter) Flush() error { return nil }
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(rec internal.ManifestRecord) error {
+	if !w.wroteHeader {
+		if err := w.w.Write(csvFields); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+	return w.w.Write([]string{
+		rec.ConceptID, rec.RevisionID, rec.Name, rec.Collection, rec.URL, rec.Path,
+		strconv.FormatInt(rec.Size, 10), rec.Checksum, rec.ChecksumAlg,
+		rec.FetchedAt.Format(time.RFC3339),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	if !w.wroteHeader {
+		if err := w.w.Write(csvFields); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
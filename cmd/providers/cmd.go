@@ -27,13 +27,24 @@ This is mostly useful for to discover provider names. Once you have a provider n
 is generally easier and faster to use the collections command.
 
 NOTE: This downloads a large JSON database from CMR and the initial download can 
-sometimes take a long time. The result is cached for 30d after initial download.
+sometimes take a long time. The result is cached for 30d after initial download; see
+--cache-ttl and --refresh.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		names, err := cmd.Flags().GetStringSlice("name")
 		failOnError(err)
 
-		if err := do(names); err != nil {
+		env, err := internal.CMREnvFromFlags(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		cache, err := internal.ResponseCacheFromFlags(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		if err := do(env, cache, names); err != nil {
 			log.Fatalf("failed! %s", err)
 		}
 
@@ -45,10 +56,11 @@ func init() {
 	flags := Cmd.Flags()
 
 	flags.StringSliceP("name", "n", nil, "List collections available for providers with given name(s)")
+	internal.AddCacheFlags(flags, internal.HoldingsCacheTTL)
 }
 
-func do(names []string) error {
-	allProviders, err := internal.GetProviderHoldings()
+func do(env internal.CMREnv, cache *internal.ResponseCache, names []string) error {
+	allProviders, err := internal.GetProviderHoldings(env, cache)
 	if err != nil {
 		return fmt.Errorf("fetching provider holdings: %w", err)
 	}
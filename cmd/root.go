@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"github.com/bmflynn/cmrfetch/cmd/cache"
 	"github.com/bmflynn/cmrfetch/cmd/collections"
 	"github.com/bmflynn/cmrfetch/cmd/granules"
 	"github.com/bmflynn/cmrfetch/cmd/keywords"
+	"github.com/bmflynn/cmrfetch/cmd/manifest"
 	"github.com/bmflynn/cmrfetch/cmd/providers"
 	"github.com/bmflynn/cmrfetch/internal"
 	"github.com/spf13/cobra"
@@ -39,10 +41,16 @@ Project: https://github.com/bmflynn/cmrfetch
 }
 
 func init() {
+	internal.AddCMREnvFlags(rootCmd.PersistentFlags())
+
 	rootCmd.AddCommand(collections.Cmd)
 	rootCmd.AddCommand(granules.Cmd)
 	rootCmd.AddCommand(keywords.Cmd)
 	rootCmd.AddCommand(providers.Cmd)
+	rootCmd.AddCommand(granules.SyncCmd)
+	rootCmd.AddCommand(granules.VerifyCmd)
+	rootCmd.AddCommand(manifest.Cmd)
+	rootCmd.AddCommand(cache.Cmd)
 }
 
 func Execute() error {
@@ -6,6 +6,7 @@ require (
 	github.com/jdxcode/netrc v0.0.0-20221124155335-4616370d1a84
 	github.com/stretchr/testify v1.8.2
 	github.com/tidwall/gjson v1.14.4
+	go.etcd.io/bbolt v1.3.9
 )
 
 require (
@@ -14,7 +15,7 @@ require (
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
-	golang.org/x/sys v0.1.0 // indirect
+	golang.org/x/sys v0.4.0 // indirect
 )
 
 require (
@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// attributeTypes are the additional attribute types supported by CMR and a validator for
+// each type's values.
+var attributeTypes = map[string]func(string) error{
+	"string": func(string) error { return nil },
+	"int": func(s string) error {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err
+	},
+	"float": func(s string) error {
+		_, err := strconv.ParseFloat(s, 64)
+		return err
+	},
+	"date": func(s string) error {
+		_, err := time.Parse("2006-01-02", s)
+		return err
+	},
+	"datetime": func(s string) error {
+		_, err := time.Parse(time.RFC3339, s)
+		return err
+	},
+	"time": func(s string) error {
+		_, err := time.Parse("15:04:05Z", s)
+		return err
+	},
+}
+
+// AttributeFilter is a product specific additional attribute (PSA) search filter.
+type AttributeFilter struct {
+	Type string
+	Name string
+	// A single value, or a range of 2 values where either may be empty for an open range
+	Values []string
+}
+
+// splitEscaped splits s on commas that are not escaped with a backslash, unescaping the parts
+func splitEscaped(s string) []string {
+	parts := []string{}
+	cur := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			cur.WriteByte(',')
+			i++
+		case s[i] == ',':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(s[i])
+		}
+	}
+	return append(parts, cur.String())
+}
+
+// ParseAttributeFilter parses an attribute filter as <type>,<name>,<value> or
+// <type>,<name>,<min>,<max>, where either min or max may be omitted for an open range. Type is
+// one of string, int, float, date, datetime, or time and commas in names or values may be
+// escaped with a backslash.
+func ParseAttributeFilter(s string) (AttributeFilter, error) {
+	parts := splitEscaped(s)
+	if len(parts) < 3 || len(parts) > 4 {
+		return AttributeFilter{}, fmt.Errorf(
+			"expected <type>,<name>,<value> or <type>,<name>,<min>,<max>, got %q", s)
+	}
+	f := AttributeFilter{
+		Type:   strings.ToLower(strings.TrimSpace(parts[0])),
+		Name:   parts[1],
+		Values: parts[2:],
+	}
+	validate, ok := attributeTypes[f.Type]
+	if !ok {
+		return f, fmt.Errorf("invalid attribute type %q; expected one of %s",
+			f.Type, strings.Join(AttributeTypes(), ", "))
+	}
+	if f.Name == "" {
+		return f, fmt.Errorf("attribute name is required")
+	}
+	if len(f.Values) == 2 {
+		if f.Type == "string" {
+			return f, fmt.Errorf("ranges are not supported for string attributes")
+		}
+		if f.Values[0] == "" && f.Values[1] == "" {
+			return f, fmt.Errorf("at least one of min or max is required for attribute ranges")
+		}
+	}
+	for _, v := range f.Values {
+		if v == "" && len(f.Values) == 2 {
+			continue
+		}
+		if err := validate(v); err != nil {
+			return f, fmt.Errorf("invalid %s value %q for attribute %s", f.Type, v, f.Name)
+		}
+	}
+	return f, nil
+}
+
+// AttributeTypes returns the supported attribute types
+func AttributeTypes() []string {
+	return []string{"string", "int", "float", "date", "datetime", "time"}
+}
+
+// String encodes the filter as a CMR attribute[] param value
+func (f AttributeFilter) String() string {
+	escape := strings.NewReplacer(",", `\,`).Replace
+	parts := []string{f.Type, escape(f.Name)}
+	for _, v := range f.Values {
+		parts = append(parts, escape(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+// addAttributeParams adds an attribute search param for each filter. A search matches all of
+// the filters.
+func addAttributeParams(query url.Values, filters []AttributeFilter) {
+	for _, f := range filters {
+		query.Add("attribute[]", f.String())
+	}
+}
+
+// AttributeFieldPrefix is the output field prefix for additional attributes, e.g.,
+// attribute.HORIZONTALTILENUMBER
+const AttributeFieldPrefix = "attribute."
+
+// decodeGranuleAttributes returns UMM-G AdditionalAttributes values by name
+func decodeGranuleAttributes(zult gjson.Result) map[string][]string {
+	attrs := map[string][]string{}
+	for _, attr := range zult.Array() {
+		vals := []string{}
+		for _, v := range attr.Get("Values").Array() {
+			vals = append(vals, v.String())
+		}
+		attrs[attr.Get("Name").String()] = vals
+	}
+	return attrs
+}
+
+// decodeCollectionAttributes returns UMM-C AdditionalAttributes by name. The value is the
+// attribute value if it has one, otherwise its data type and range, e.g., INT 1..36.
+func decodeCollectionAttributes(zult gjson.Result) map[string]string {
+	attrs := map[string]string{}
+	for _, attr := range zult.Array() {
+		val := attr.Get("Value").String()
+		if val == "" {
+			val = attr.Get("DataType").String()
+			begin := attr.Get("ParameterRangeBegin").String()
+			end := attr.Get("ParameterRangeEnd").String()
+			if begin != "" || end != "" {
+				val += fmt.Sprintf(" %s..%s", begin, end)
+			}
+		}
+		attrs[attr.Get("Name").String()] = val
+	}
+	return attrs
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestParseAttributeFilter(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected AttributeFilter
+		Encoded  string
+	}{
+		{"int,HORIZONTALTILENUMBER,8", AttributeFilter{"int", "HORIZONTALTILENUMBER", []string{"8"}}, "int,HORIZONTALTILENUMBER,8"},
+		{"FLOAT,CLOUD,1.5,", AttributeFilter{"float", "CLOUD", []string{"1.5", ""}}, "float,CLOUD,1.5,"},
+		{"date,D,,2023-01-01", AttributeFilter{"date", "D", []string{"", "2023-01-01"}}, "date,D,,2023-01-01"},
+		{"datetime,DT,2023-01-01T00:00:00Z", AttributeFilter{"datetime", "DT", []string{"2023-01-01T00:00:00Z"}}, "datetime,DT,2023-01-01T00:00:00Z"},
+		{"time,T,12:00:00Z,13:00:00Z", AttributeFilter{"time", "T", []string{"12:00:00Z", "13:00:00Z"}}, "time,T,12:00:00Z,13:00:00Z"},
+		{`string,NAME,a\,b`, AttributeFilter{"string", "NAME", []string{"a,b"}}, `string,NAME,a\,b`},
+	}
+	for _, test := range cases {
+		t.Run(test.Value, func(t *testing.T) {
+			f, err := ParseAttributeFilter(test.Value)
+			require.NoError(t, err)
+			require.Equal(t, test.Expected, f)
+			require.Equal(t, test.Encoded, f.String())
+		})
+	}
+
+	invalid := []string{
+		"int,NAME",
+		"int,NAME,1,2,3",
+		"bool,NAME,true",
+		"int,,1",
+		"int,NAME,1.5",
+		"float,NAME,x",
+		"date,NAME,2023-13-01",
+		"string,NAME,a,b",
+		"int,NAME,,",
+	}
+	for _, s := range invalid {
+		t.Run("invalid "+s, func(t *testing.T) {
+			_, err := ParseAttributeFilter(s)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeAttributes(t *testing.T) {
+	granAttrs := decodeGranuleAttributes(gjson.Parse(`[
+		{"Name": "HORIZONTALTILENUMBER", "Values": ["8"]},
+		{"Name": "MULTI", "Values": ["a", "b"]}
+	]`))
+	require.Equal(t, map[string][]string{
+		"HORIZONTALTILENUMBER": {"8"},
+		"MULTI":                {"a", "b"},
+	}, granAttrs)
+
+	colAttrs := decodeCollectionAttributes(gjson.Parse(`[
+		{"Name": "HORIZONTALTILENUMBER", "DataType": "INT", "ParameterRangeBegin": "0", "ParameterRangeEnd": "35"},
+		{"Name": "PROCESSVERSION", "DataType": "STRING", "Value": "6.1"},
+		{"Name": "QA", "DataType": "STRING"}
+	]`))
+	require.Equal(t, map[string]string{
+		"HORIZONTALTILENUMBER": "INT 0..35",
+		"PROCESSVERSION":       "6.1",
+		"QA":                   "STRING",
+	}, colAttrs)
+}
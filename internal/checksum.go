@@ -2,54 +2,169 @@ package internal
 
 import (
 	"crypto/md5"
+	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"hash"
+	"hash/adler32"
+	"hash/crc32"
 	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bmflynn/cmrfetch/internal/log"
 )
 
-// ChecksumAlgSupported returns true if we support the named alg.
+// checksumAlgs is the registry of supported checksum algorithms, keyed by the UMM-G Checksum
+// Algorithm name.
 //
-// This does not include all the algs listed in the UMM-G.
+// This covers the UMM-G algorithms except for those in unsupportedChecksumAlgs, plus CRC32
+// which is used by some providers. Checksums are hex encoded, including those such as POSIX
+// that the equivalent command line tools print in decimal, see ChecksumsEqual.
 // See https://wiki.earthdata.nasa.gov/display/CMR/Archive+And+Distribution+Information+for+Granules
+var checksumAlgs = map[string]func() hash.Hash{
+	"Adler-32":     func() hash.Hash { return adler32.New() },
+	"BSD checksum": func() hash.Hash { return &bsdSum{} },
+	"CRC32":        func() hash.Hash { return crc32.NewIEEE() },
+	"Fletcher-16":  func() hash.Hash { return newFletcher16() },
+	"Fletcher-32":  func() hash.Hash { return newFletcher32() },
+	"Fletcher-64":  func() hash.Hash { return newFletcher64() },
+	"MD5":          md5.New,
+	"POSIX":        func() hash.Hash { return &posixCksum{} },
+	"SHA-1":        sha1.New,
+	"SHA-256":      sha256.New,
+	"SHA-384":      sha512.New384,
+	"SHA-512":      sha512.New,
+	"SYSV":         func() hash.Hash { return &sysvSum{} },
+}
+
+// integerChecksumAlgs are the algorithms whose checksums are integers, which may be published
+// in decimal or in hex without leading zeros
+var integerChecksumAlgs = map[string]bool{
+	"Adler-32":     true,
+	"BSD checksum": true,
+	"CRC32":        true,
+	"Fletcher-16":  true,
+	"Fletcher-32":  true,
+	"Fletcher-64":  true,
+	"POSIX":        true,
+	"SYSV":         true,
+}
+
+// unsupportedChecksumAlgs are the UMM-G algorithms that cannot be verified, with the reason.
+var unsupportedChecksumAlgs = map[string]string{
+	"SHA-2": "the digest size is not specified",
+	"SM3":   "not implemented",
+}
+
+// unsupported algs that have already been logged
+var loggedChecksumAlgs sync.Map
+
+// normalizeChecksumAlg removes case and separator differences, e.g., SHA-256, sha256, and
+// sha_256 are all the same algorithm.
+func normalizeChecksumAlg(alg string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToUpper(strings.TrimSpace(alg)))
+}
+
+// normalized name to registry name
+var normalizedChecksumAlgs = func() map[string]string {
+	algs := map[string]string{}
+	for name := range checksumAlgs {
+		algs[normalizeChecksumAlg(name)] = name
+	}
+	return algs
+}()
+
+// CanonicalChecksumAlg returns the registry name for alg, e.g., SHA-256 for sha256, and whether
+// alg is supported.
+func CanonicalChecksumAlg(alg string) (string, bool) {
+	name, ok := normalizedChecksumAlgs[normalizeChecksumAlg(alg)]
+	return name, ok
+}
+
+// ChecksumAlgs returns the names of all supported checksum algorithms
+func ChecksumAlgs() []string {
+	names := []string{}
+	for name := range checksumAlgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newHash(alg string) (hash.Hash, error) {
+	name, ok := CanonicalChecksumAlg(alg)
+	if !ok {
+		return nil, unsupportedChecksumAlgError(alg)
+	}
+	return checksumAlgs[name](), nil
+}
+
+// unsupportedChecksumAlgError returns the error for an unsupported alg. UMM-G algorithms that
+// are not supported are logged, once per algorithm, so it is clear why checksums were not
+// verified.
+func unsupportedChecksumAlgError(alg string) error {
+	for name, reason := range unsupportedChecksumAlgs {
+		if normalizeChecksumAlg(name) != normalizeChecksumAlg(alg) {
+			continue
+		}
+		err := fmt.Errorf("%s checksums are not supported, %s", name, reason)
+		if _, logged := loggedChecksumAlgs.LoadOrStore(name, true); !logged {
+			log.Printf("WARNING: %s", err)
+		}
+		return err
+	}
+	return fmt.Errorf("expected one of %s, got %q", strings.Join(ChecksumAlgs(), ", "), alg)
+}
+
+// ChecksumAlgSupported returns true if we support the named alg. Names are case insensitive and
+// separators are ignored, e.g., SHA-256 and sha256 are equivalent.
 func ChecksumAlgSupported(alg string) bool {
-	switch alg {
-	case "SHA-256":
-		return true
-	case "SHA-384":
-		return true
-	case "SHA-512":
-		return true
-	case "MD5":
-		return true
-	default:
-		return false
-	}
-}
-
-// Checksum performs the alg checksum for the file at path. Alg must be one
-// of SHA-256, SHA-384, SHA-512, or MD5.
+	_, ok := CanonicalChecksumAlg(alg)
+	return ok
+}
+
+// ChecksumsEqual compares a computed hex encoded alg checksum, such as from Checksum, to a
+// published checksum, ignoring case and surrounding whitespace. For integer valued algorithms,
+// e.g., POSIX or CRC32, the values are compared as numbers and the published checksum may be
+// decimal or hex.
+func ChecksumsEqual(alg, computed, published string) bool {
+	computed, published = strings.TrimSpace(computed), strings.TrimSpace(published)
+	if name, ok := CanonicalChecksumAlg(alg); ok && integerChecksumAlgs[name] {
+		val, err := strconv.ParseUint(computed, 16, 64)
+		if err != nil {
+			return false
+		}
+		// a published value of only digits is ambiguous, so either base may match
+		published = strings.ToLower(published)
+		if strings.HasPrefix(published, "0x") {
+			published = published[2:]
+		} else if dec, err := strconv.ParseUint(published, 10, 64); err == nil && dec == val {
+			return true
+		}
+		hexVal, err := strconv.ParseUint(published, 16, 64)
+		return err == nil && hexVal == val
+	}
+	return strings.EqualFold(computed, published)
+}
+
+// Checksum performs the alg checksum for the file at path, returning the hex encoded value.
+// Alg must be supported, see ChecksumAlgSupported.
 func Checksum(alg, path string) (string, error) {
+	hash, err := newHash(alg)
+	if err != nil {
+		return "", fmt.Errorf("%s checksum not supported", alg)
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
-	var hash hash.Hash
-	switch alg {
-	case "SHA-256":
-		hash = sha256.New()
-	case "SHA-384":
-		hash = sha512.New384()
-	case "SHA-512":
-		hash = sha512.New()
-	case "MD5":
-		hash = md5.New()
-	default:
-		return "", fmt.Errorf("%s checksum not supported", alg)
-	}
+	defer f.Close()
 	if _, err = io.Copy(hash, f); err != nil {
 		return "", err
 	}
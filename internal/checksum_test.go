@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(fpath, []byte("abcde"), 0o644))
+
+	tests := []struct {
+		alg      string
+		expected string
+	}{
+		{"Adler-32", "05c801f0"},
+		{"BSD checksum", "10c2"},
+		{"CRC32", "8587d865"},
+		{"Fletcher-16", "c8f0"},
+		{"Fletcher-32", "f04fc729"},
+		{"Fletcher-64", "c8c6c527646362c6"},
+		{"MD5", "ab56b4d92b40713acc5af89985d4b786"},
+		{"POSIX", "3b691385"},
+		{"SHA-1", "03de6c570bfe24bfc328ccd7ca46b76eadaf4334"},
+		{"SHA-256", "36bbe50ed96841d10443bcb670d6554f0a34b761be67ec9c4a8ad2c0c44ca42c"},
+		{"SHA-384", "4c525cbeac729eaf4b4665815bc5db0c84fe6300068a727cf74e2813521565abc0ec57a37ee4d8be89d097c0d2ad52f0"},
+		{"SHA-512", "878ae65a92e86cac011a570d4c30a7eaec442b85ce8eca0c2952b5e3cc0628c2e79d889ad4d5c7c626986d452dd86374b6ffaa7cd8b67665bef2289a5c70b0a1"},
+		{"SYSV", "01ef"},
+	}
+	for _, test := range tests {
+		t.Run(test.alg, func(t *testing.T) {
+			require.True(t, ChecksumAlgSupported(test.alg))
+			checksum, err := Checksum(test.alg, fpath)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, checksum)
+
+			// inline download verification uses the same registry
+			hash, err := newHash(test.alg)
+			require.NoError(t, err)
+			_, err = hash.Write([]byte("abcde"))
+			require.NoError(t, err)
+			require.Equal(t, test.expected, hex.EncodeToString(hash.Sum(nil)))
+		})
+	}
+
+	require.Len(t, ChecksumAlgs(), len(tests))
+
+	_, err := Checksum("SNEFRU", fpath)
+	require.Error(t, err)
+
+	_, err = newHash("sha2")
+	require.ErrorContains(t, err, "SHA-2 checksums are not supported")
+}
+
+func TestChecksumAlgSupported(t *testing.T) {
+	for _, alg := range []string{
+		"sha256", "SHA-256", "sha-384", "ADLER32", "adler-32", "crc32", "fletcher-32", "sha1",
+		"bsd checksum", "posix", "sysv",
+	} {
+		require.True(t, ChecksumAlgSupported(alg), alg)
+	}
+	for _, alg := range []string{"", "xxx", "SHA-2", "SM3", "MD4"} {
+		require.False(t, ChecksumAlgSupported(alg), alg)
+	}
+}
+
+func TestChecksumsEqual(t *testing.T) {
+	require.True(t, ChecksumsEqual("MD5", "ABCDEF", "abcdef"))
+	require.True(t, ChecksumsEqual("MD5", " abcdef\n", "abcdef"))
+	require.False(t, ChecksumsEqual("MD5", "abcdef", "abcdee"))
+	require.False(t, ChecksumsEqual("MD5", "0abcdef", "abcdef"), "digests are not numbers")
+
+	// published values are from cksum, sum -r, and sum -s for "abcde"
+	require.True(t, ChecksumsEqual("POSIX", "3b691385", "996742021"))
+	require.True(t, ChecksumsEqual("posix", "3b691385", "3B691385"))
+	require.True(t, ChecksumsEqual("BSD checksum", "10c2", "04290"))
+	require.True(t, ChecksumsEqual("SYSV", "01ef", "495"))
+	require.False(t, ChecksumsEqual("POSIX", "3b691385", "996742022"))
+
+	// hex without leading zeros
+	require.True(t, ChecksumsEqual("Adler-32", "05c801f0", "5c801f0"))
+	require.True(t, ChecksumsEqual("CRC32", "05c801f0", "0x5C801F0"))
+	require.True(t, ChecksumsEqual("Fletcher-16", "00ff", "255"))
+	require.False(t, ChecksumsEqual("CRC32", "05c801f0", "xyz"))
+}
+
+func TestFletcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		new      func() *fletcher
+		input    string
+		expected string
+	}{
+		{"16", newFletcher16, "abcdef", "2057"},
+		{"32", newFletcher32, "abcdef", "56502d2a"},
+		{"32", newFletcher32, "abcdefgh", "ebe19591"},
+		{"64", newFletcher64, "abcdef", "c8c72b276463c8c6"},
+		{"64", newFletcher64, "abcdefgh", "312e2b28cccac8c6"},
+	}
+	for _, test := range tests {
+		t.Run(test.name+"/"+test.input, func(t *testing.T) {
+			f := test.new()
+			_, _ = f.Write([]byte(test.input))
+			require.Equal(t, test.expected, hex.EncodeToString(f.Sum(nil)))
+
+			// writes split mid-word give the same result
+			f.Reset()
+			for i := 0; i < len(test.input); i++ {
+				_, _ = f.Write([]byte{test.input[i]})
+			}
+			require.Equal(t, test.expected, hex.EncodeToString(f.Sum(nil)))
+		})
+	}
+}
+
+func TestUnixSums(t *testing.T) {
+	long := make([]byte, 0, 256*300)
+	for i := 0; i < 300; i++ {
+		for b := 0; b < 256; b++ {
+			long = append(long, byte(b))
+		}
+	}
+
+	// expected values are from cksum, sum -r, and sum -s
+	tests := []struct {
+		name     string
+		new      func() hash.Hash
+		input    []byte
+		expected string
+	}{
+		{"posix", func() hash.Hash { return &posixCksum{} }, nil, "ffffffff"},
+		{"posix", func() hash.Hash { return &posixCksum{} }, []byte("123456789"), "377a6011"},
+		{"posix", func() hash.Hash { return &posixCksum{} }, long, "30f7994d"},
+		{"bsd", func() hash.Hash { return &bsdSum{} }, []byte("123456789"), "d16f"},
+		{"bsd", func() hash.Hash { return &bsdSum{} }, long, "5800"},
+		{"sysv", func() hash.Hash { return &sysvSum{} }, []byte("123456789"), "01dd"},
+		{"sysv", func() hash.Hash { return &sysvSum{} }, long, "6a95"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%d", test.name, len(test.input)), func(t *testing.T) {
+			h := test.new()
+			_, _ = h.Write(test.input)
+			require.Equal(t, test.expected, hex.EncodeToString(h.Sum(nil)))
+
+			// split writes give the same result and Sum does not change the state
+			h.Reset()
+			for i := 0; i < len(test.input); i += 1000 {
+				end := i + 1000
+				if end > len(test.input) {
+					end = len(test.input)
+				}
+				_, _ = h.Write(test.input[i:end])
+				h.Sum(nil)
+			}
+			require.Equal(t, test.expected, hex.EncodeToString(h.Sum(nil)))
+		})
+	}
+}
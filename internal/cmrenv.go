@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CMREnv describes a CMR deployment and the Earthdata Login service that goes with it.
+type CMREnv struct {
+	// Name of the environment, e.g., prod, uat, sit
+	Name string
+	// URL is the CMR base url, without the /search path
+	URL string
+	// EDLHost is the Earthdata Login host used to authenticate for this environment
+	EDLHost string
+}
+
+var CMREnvs = map[string]CMREnv{
+	"prod": {Name: "prod", URL: defaultCMRURL, EDLHost: "urs.earthdata.nasa.gov"},
+	"uat":  {Name: "uat", URL: "https://cmr.uat.earthdata.nasa.gov", EDLHost: "uat.urs.earthdata.nasa.gov"},
+	"sit":  {Name: "sit", URL: "https://cmr.sit.earthdata.nasa.gov", EDLHost: "sit.urs.earthdata.nasa.gov"},
+}
+
+// CMREnvNames returns the sorted names of the known CMR environments.
+func CMREnvNames() []string {
+	names := []string{}
+	for name := range CMREnvs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveCMREnv returns the named environment. If cmrURL is not empty it is used in place
+// of the environment's CMR url, e.g., for testing against a local CMR mock service.
+func ResolveCMREnv(name, cmrURL string) (CMREnv, error) {
+	env, ok := CMREnvs[strings.ToLower(name)]
+	if !ok {
+		return env, fmt.Errorf("unknown CMR environment %q, expected one of %s",
+			name, strings.Join(CMREnvNames(), ", "))
+	}
+	if cmrURL != "" {
+		u, err := url.Parse(cmrURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return env, fmt.Errorf("invalid CMR url %q", cmrURL)
+		}
+		env.URL = strings.TrimSuffix(cmrURL, "/")
+	}
+	return env, nil
+}
+
+// SearchURL is the url of the CMR search API for this environment.
+func (e CMREnv) SearchURL() string {
+	return strings.TrimSuffix(e.URL, "/") + "/search"
+}
+
+// cacheKey is a filesystem safe name for the environment that can be used to keep cached data
+// from different environments separate. The production environment has an empty key.
+func (e CMREnv) cacheKey() string {
+	if strings.TrimSuffix(e.URL, "/") == defaultCMRURL {
+		return ""
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil || u.Host == "" {
+		return e.Name
+	}
+	return regexp.MustCompile(`[^\w.-]+`).ReplaceAllString(u.Host, "_")
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCMREnv(t *testing.T) {
+	env, err := ResolveCMREnv("prod", "")
+	require.NoError(t, err)
+	require.Equal(t, "https://cmr.earthdata.nasa.gov/search", env.SearchURL())
+	require.Equal(t, "urs.earthdata.nasa.gov", env.EDLHost)
+	require.Equal(t, "", env.cacheKey())
+
+	env, err = ResolveCMREnv("UAT", "")
+	require.NoError(t, err)
+	require.Equal(t, "https://cmr.uat.earthdata.nasa.gov/search", env.SearchURL())
+	require.Equal(t, "uat.urs.earthdata.nasa.gov", env.EDLHost)
+	require.Equal(t, "cmr.uat.earthdata.nasa.gov", env.cacheKey())
+
+	env, err = ResolveCMREnv("sit", "http://localhost:3003/")
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:3003/search", env.SearchURL())
+	require.Equal(t, "sit.urs.earthdata.nasa.gov", env.EDLHost)
+	require.Equal(t, "localhost_3003", env.cacheKey())
+
+	_, err = ResolveCMREnv("xxx", "")
+	require.Error(t, err, "unknown env should be an error")
+
+	_, err = ResolveCMREnv("prod", "localhost")
+	require.Error(t, err, "url without scheme should be an error")
+}
+
+func TestCMRSearchAPIWithURL(t *testing.T) {
+	paths := []string{}
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("cmr-hits", "0")
+		_, _ = w.Write([]byte(`{"items": []}`))
+	}))
+	defer svr.Close()
+
+	env, err := ResolveCMREnv("prod", svr.URL)
+	require.NoError(t, err)
+	api := NewCMRSearchAPI().WithURL(env.SearchURL())
+
+	zult, err := api.SearchGranules(context.Background(), NewSearchGranuleParams())
+	require.NoError(t, err)
+	for range zult.Ch {
+	}
+	require.NoError(t, zult.Err())
+
+	zult2, err := api.SearchCollections(context.Background(), NewSearchCollectionParams())
+	require.NoError(t, err)
+	for range zult2.Ch {
+	}
+	require.NoError(t, zult2.Err())
+
+	require.Equal(t, []string{"/search/granules.umm_json", "/search/collections.umm_json"}, paths)
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bmflynn/cmrfetch/internal/log"
 	"github.com/tidwall/gjson"
@@ -19,10 +20,20 @@ var (
 	defaultCMRSearchURL = defaultCMRURL + "/search"
 )
 
+// Search pages are retried a little more persistently than downloads because a single
+// failed page fails the entire search
+var defaultSearchRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
 type CMRSearchAPI struct {
 	url      string
 	client   *http.Client
 	pageSize int
+	retry    RetryPolicy
+	cache    *ResponseCache
 }
 
 func NewCMRSearchAPI() *CMRSearchAPI {
@@ -30,41 +41,117 @@ func NewCMRSearchAPI() *CMRSearchAPI {
 		url:      defaultCMRSearchURL,
 		client:   http.DefaultClient,
 		pageSize: 200,
+		retry:    defaultSearchRetryPolicy,
 	}
 }
 
+// WithURL sets the CMR search API url, e.g., the result of CMREnv.SearchURL.
+func (api *CMRSearchAPI) WithURL(searchURL string) *CMRSearchAPI {
+	api.url = strings.TrimSuffix(searchURL, "/")
+	return api
+}
+
+// WithCache sets the cache used for search responses. A nil cache disables caching.
+func (api *CMRSearchAPI) WithCache(cache *ResponseCache) *CMRSearchAPI {
+	api.cache = cache
+	return api
+}
+
+// WithRetryPolicy sets the policy used to retry failed page requests.
+func (api *CMRSearchAPI) WithRetryPolicy(policy RetryPolicy) *CMRSearchAPI {
+	api.retry = policy
+	return api
+}
+
+// ScrollResult provides search results on Ch, which is closed once all results have been
+// provided or an error occurs. Results are produced in the background until the context used
+// for the search is done, so receivers that stop reading from Ch before it is closed must
+// cancel that context.
 type ScrollResult[T Granule | Collection | gjson.Result | Facet] struct {
 	Ch   chan T
-	err  error
 	hits int
-	mu   *sync.Mutex
+	// Shared by all copies of the result so errors that occur while scrolling are visible
+	// to the receiver
+	state *scrollState
 }
 
-func newScrollResult[T Granule | Collection | gjson.Result | Facet]() ScrollResult[T] {
+type scrollState struct {
+	mu  sync.Mutex
+	err error
+	// done is the search context's Done channel, stopping any goroutines sending results
+	done <-chan struct{}
+}
+
+func newScrollResult[T Granule | Collection | gjson.Result | Facet](ctx context.Context) ScrollResult[T] {
 	return ScrollResult[T]{
-		Ch: make(chan T),
-		mu: &sync.Mutex{},
+		Ch:    make(chan T),
+		state: &scrollState{done: ctx.Done()},
 	}
 }
 
 func (r *ScrollResult[T]) setErr(err error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.err = err
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.err = err
 }
 
+// Err returns any error that occurred while scrolling. It should be checked after Ch is closed.
 func (r *ScrollResult[T]) Err() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	return r.err
+	if r.state == nil {
+		return nil
+	}
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return r.state.err
 }
 
+// Hits is the number of results CMR reports for the search, or the search limit if it is
+// smaller.
 func (r ScrollResult[T]) Hits() int {
 	return r.hits
 }
 
+// Filter returns a result that only provides the items for which keep returns true. The
+// returned result shares hits, errors, and the search context with r.
+func (r ScrollResult[T]) Filter(keep func(T) bool) ScrollResult[T] {
+	zult := ScrollResult[T]{
+		Ch:    make(chan T),
+		hits:  r.hits,
+		state: r.state,
+	}
+	var done <-chan struct{}
+	if r.state != nil {
+		done = r.state.done
+	}
+	go func() {
+		defer close(zult.Ch)
+		for item := range r.Ch {
+			if !keep(item) {
+				continue
+			}
+			select {
+			case zult.Ch <- item:
+			case <-done:
+				// the search sets the context error
+				return
+			}
+		}
+	}()
+	return zult
+}
+
+// Get scrolls all pages of results for url. Failed page requests that are retryable are retried,
+// using the same search-after value, according to the API's retry policy.
 func (api *CMRSearchAPI) Get(ctx context.Context, url string) (ScrollResult[gjson.Result], error) {
-	result := newScrollResult[gjson.Result]()
+	return api.scroll(ctx, url, 0)
+}
+
+// scroll is Get, but stops once limit items have been provided if limit is greater than 0.
+// Scrolling also stops, setting the result error, if ctx is done while waiting on the receiver.
+// An error is returned if the first page fails, otherwise errors are available from the
+// result.
+func (api *CMRSearchAPI) scroll(ctx context.Context, url string, limit int) (ScrollResult[gjson.Result], error) {
+	result := newScrollResult[gjson.Result](ctx)
 
 	// only ever sent to once with initial hits value
 	hitsCh := make(chan int, 1)
@@ -74,62 +161,50 @@ func (api *CMRSearchAPI) Get(ctx context.Context, url string) (ScrollResult[gjso
 		defer close(hitsCh)
 
 		page := 1
+		sent := 0
 		var searchAfter string
 		for {
-			log.Debug("method=GET page=%v url=%s", page, url)
-			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-			if err != nil {
-				result.setErr(fmt.Errorf("create request: %w", err))
-				log.Debug("request create: %s", result.err)
-				return
-			}
-			if searchAfter != "" {
-				req.Header.Set("cmr-search-after", searchAfter)
-			}
-			resp, err := api.client.Do(req)
+			var zult searchPage
+			_, err := api.retry.Do(ctx, func() error {
+				var err error
+				zult, err = api.getPage(ctx, url, page, searchAfter)
+				return err
+			}, func(attempt int, delay time.Duration, err error) {
+				log.Printf("retrying page %d in %s, attempt %d failed: %s", page, delay.Round(time.Millisecond), attempt, err)
+			})
 			if err != nil {
-				result.setErr(fmt.Errorf("protocol error: %w", err))
-				log.Debug("request do: %s", result.err)
-				return
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				result.setErr(api.newCMRError(resp))
-				log.Debug("request != ok: %s", result.err)
+				result.setErr(err)
 				return
 			}
 
-			hits, err := strconv.Atoi(resp.Header.Get("cmr-hits"))
-			if err != nil {
-				result.setErr(fmt.Errorf("failed to parse cmr-hits header as int: %s", resp.Header.Get("cmr-hits")))
-				log.Debug("request hits: %s", result.err)
-				return
-			}
 			// Hits is the same for all pages, only send once
 			if !sentHits {
+				hits := zult.hits
+				if limit > 0 && hits > limit {
+					hits = limit
+				}
 				log.Debug("sending hits: %v", hits)
 				hitsCh <- hits
 				sentHits = true
 			}
 
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				result.setErr(fmt.Errorf("reading response: %w", err))
-				log.Debug("request read: %s", result.err)
-				return
-			}
-			items := gjson.Get(string(body), "items").Array()
-			if len(items) == 0 {
-				items = gjson.Get(string(body), "feed.entry").Array()
-			}
-
-			for _, item := range items {
-				result.Ch <- item
+			for _, item := range zult.items {
+				select {
+				case result.Ch <- item:
+				case <-ctx.Done():
+					result.setErr(ctx.Err())
+					return
+				}
+				sent++
+				if limit > 0 && sent >= limit {
+					log.Debug("limit of %d reached", limit)
+					return
+				}
 			}
 
 			// No results or empty search-after-header indicates pagination is done
-			searchAfter = resp.Header.Get("cmr-search-after")
-			if searchAfter == "" || len(items) == 0 {
+			searchAfter = zult.searchAfter
+			if searchAfter == "" || len(zult.items) == 0 {
 				log.Debug("no more results")
 				return
 			}
@@ -139,15 +214,119 @@ func (api *CMRSearchAPI) Get(ctx context.Context, url string) (ScrollResult[gjso
 
 	// Block until we've retrieved the number of hits from the header. This gives
 	// the client a chance to react to the number of hits before scrolling results
-	result.hits = <-hitsCh
+	hits, ok := <-hitsCh
+	if !ok {
+		// closed without hits, so the first page failed
+		return ScrollResult[gjson.Result]{}, result.Err()
+	}
+	result.hits = hits
 
 	return result, nil
 }
 
+// count returns the number of hits for url, which should use a page_size of 0 so no results
+// are retrieved.
+func (api *CMRSearchAPI) count(ctx context.Context, url string) (int, error) {
+	var zult searchPage
+	_, err := api.retry.Do(ctx, func() error {
+		var err error
+		zult, err = api.getPage(ctx, url, 1, "")
+		return err
+	}, func(attempt int, delay time.Duration, err error) {
+		log.Printf("retrying count in %s, attempt %d failed: %s", delay.Round(time.Millisecond), attempt, err)
+	})
+	return zult.hits, err
+}
+
+// pageSizeFor returns the page size to use for a search limited to limit results, where 0 is
+// no limit.
+func (api *CMRSearchAPI) pageSizeFor(limit int) int {
+	if limit > 0 && limit < api.pageSize {
+		return limit
+	}
+	return api.pageSize
+}
+
+type searchPage struct {
+	hits        int
+	items       []gjson.Result
+	searchAfter string
+}
+
+// getPage performs the request for a single page of results, using the cached response if
+// there is one
+func (api *CMRSearchAPI) getPage(ctx context.Context, url string, page int, searchAfter string) (searchPage, error) {
+	if header, body, ok := api.cache.Get(url, searchAfter); ok {
+		log.Debug("method=GET page=%v url=%s cached=true", page, url)
+		zult, err := newSearchPage(header, body)
+		if err == nil {
+			return zult, nil
+		}
+		log.Debug("cached page: %s", err)
+	}
+
+	log.Debug("method=GET page=%v url=%s", page, url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return searchPage{}, fmt.Errorf("create request: %w", err)
+	}
+	if searchAfter != "" {
+		req.Header.Set("cmr-search-after", searchAfter)
+	}
+	resp, err := api.client.Do(req)
+	if err != nil {
+		err = fmt.Errorf("protocol error: %w", err)
+		log.Debug("request do: %s", err)
+		return searchPage{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := api.newCMRError(resp)
+		log.Debug("request != ok: %s", err)
+		return searchPage{}, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		// Body may be truncated by a dropped connection, which is worth retrying
+		err = fmt.Errorf("reading response: %w", err)
+		log.Debug("request read: %s", err)
+		return searchPage{}, err
+	}
+	zult, err := newSearchPage(resp.Header, body)
+	if err != nil {
+		log.Debug("request hits: %s", err)
+		return zult, err
+	}
+	if err := api.cache.Put(url, searchAfter, resp.Header, body); err != nil {
+		log.Printf("WARNING: failed to cache response: %s", err)
+	}
+
+	return zult, nil
+}
+
+// newSearchPage creates a page from the response header and body
+func newSearchPage(header http.Header, body []byte) (searchPage, error) {
+	zult := searchPage{}
+	var err error
+	zult.hits, err = strconv.Atoi(header.Get("cmr-hits"))
+	if err != nil {
+		return zult, fmt.Errorf("failed to parse cmr-hits header as int: %s", header.Get("cmr-hits"))
+	}
+	zult.items = gjson.GetBytes(body, "items").Array()
+	if len(zult.items) == 0 {
+		zult.items = gjson.GetBytes(body, "feed.entry").Array()
+	}
+	zult.searchAfter = header.Get("cmr-search-after")
+	return zult, nil
+}
+
 func (api *CMRSearchAPI) newCMRError(resp *http.Response) error {
 	cmrErr := &CMRError{
-		Status:    resp.Status,
-		RequestID: resp.Header.Get("cmr-request-id"),
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("cmr-request-id"),
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 	}
 	// attempt to unmarshal what we think errors from CMR should look like
 	body, _ := io.ReadAll(resp.Body)
@@ -36,18 +36,19 @@ func parseTemporalExtents(gj gjson.Result) []string {
 
 func newCollectionFromUMM(gj gjson.Result) Collection {
 	col := Collection{
-		"shortname":        gj.Get("umm.ShortName").String(),
-		"title":            gj.Get("umm.EntryTitle").String(),
-		"version":          gj.Get("umm.Version").String(),
-		"concept_id":       gj.Get("meta.concept-id").String(),
-		"processing_level": gj.Get("umm.ProcessingLevel.Id").String(),
-		"doi":              strings.TrimSpace(gj.Get("umm.DOI.DOI").String()),
-		"provider":         gj.Get("meta.provider-id").String(),
-		"revision_id":      gj.Get("meta.revision-id").String(),
-		"revision_date":    gj.Get("meta.revision-date").String(),
-		"abstract":         gj.Get("umm.Abstract").String(),
-		"data_type":        gj.Get("umm.CollectionDataType").String(),
-		"temporal_extents": strings.Join(parseTemporalExtents(gj), "\n"),
+		"shortname":          gj.Get("umm.ShortName").String(),
+		"title":              gj.Get("umm.EntryTitle").String(),
+		"version":            gj.Get("umm.Version").String(),
+		"concept_id":         gj.Get("meta.concept-id").String(),
+		"processing_level":   gj.Get("umm.ProcessingLevel.Id").String(),
+		"doi":                strings.TrimSpace(gj.Get("umm.DOI.DOI").String()),
+		"provider":           gj.Get("meta.provider-id").String(),
+		"revision_id":        gj.Get("meta.revision-id").String(),
+		"revision_date":      gj.Get("meta.revision-date").String(),
+		"abstract":           gj.Get("umm.Abstract").String(),
+		"data_type":          gj.Get("umm.CollectionDataType").String(),
+		"temporal_extents":   strings.Join(parseTemporalExtents(gj), "\n"),
+		"s3_credentials_url": gj.Get("umm.DirectDistributionInformation.S3CredentialsAPIEndpoint").String(),
 	}
 	instruments := []string{}
 	for _, plat := range gj.Get("umm.Platforms").Array() {
@@ -65,6 +66,9 @@ func newCollectionFromUMM(gj gjson.Result) Collection {
 		}
 	}
 	col["infourls"] = strings.Join(urls, "\n")
+	for name, val := range decodeCollectionAttributes(gj.Get("umm.AdditionalAttributes")) {
+		col[AttributeFieldPrefix+name] = val
+	}
 	return col
 }
 
@@ -75,9 +79,11 @@ type SearchCollectionParams struct {
 	platforms      []string
 	instruments    []string
 	shortnames     []string
+	versions       []string
 	titlePattern   string
 	updatedSince   *time.Time
 	granulesAdded  *TimeRange
+	temporal       *TimeRange
 	cloudHosted    bool
 	cloudHostedSet bool
 	hasGranules    bool
@@ -86,6 +92,9 @@ type SearchCollectionParams struct {
 	standardSet    bool
 	sortField      string
 	dataType       string
+	spatial        spatialParams
+	attributes     []AttributeFilter
+	limit          int
 }
 
 func NewSearchCollectionParams() *SearchCollectionParams {
@@ -107,6 +116,11 @@ func (p *SearchCollectionParams) ShortNames(names ...string) *SearchCollectionPa
 	return p
 }
 
+func (p *SearchCollectionParams) Versions(versions ...string) *SearchCollectionParams {
+	p.versions = versions
+	return p
+}
+
 func (p *SearchCollectionParams) Platforms(names ...string) *SearchCollectionParams {
 	p.platforms = names
 	return p
@@ -132,6 +146,18 @@ func (p *SearchCollectionParams) GranulesAdded(tr TimeRange) *SearchCollectionPa
 	return p
 }
 
+// Attributes limits results to collections matching all of the additional attribute filters
+func (p *SearchCollectionParams) Attributes(filters ...AttributeFilter) *SearchCollectionParams {
+	p.attributes = filters
+	return p
+}
+
+// Timerange limits results to collections with a temporal extent overlapping tr
+func (p *SearchCollectionParams) Timerange(tr TimeRange) *SearchCollectionParams {
+	p.temporal = &tr
+	return p
+}
+
 func (p *SearchCollectionParams) CloudHosted(b bool) *SearchCollectionParams {
 	p.cloudHostedSet = true
 	p.cloudHosted = b
@@ -164,6 +190,46 @@ func (p *SearchCollectionParams) DataType(val string) *SearchCollectionParams {
 	return p
 }
 
+// BoundingBox limits results to collections overlapping a bounding box, as
+// west,south,east,north
+func (p *SearchCollectionParams) BoundingBox(vals []float64) *SearchCollectionParams {
+	p.spatial.boundingBox = vals
+	return p
+}
+
+// Point limits results to collections containing a point, as lon,lat
+func (p *SearchCollectionParams) Point(vals []float64) *SearchCollectionParams {
+	p.spatial.point = vals
+	return p
+}
+
+// Circle limits results to collections overlapping a circle, as lon,lat,radius where radius is
+// in meters
+func (p *SearchCollectionParams) Circle(vals []float64) *SearchCollectionParams {
+	p.spatial.circle = vals
+	return p
+}
+
+// Polygon limits results to collections overlapping a counter-clockwise polygon, as
+// lon1,lat1,lon2,lat2,...
+func (p *SearchCollectionParams) Polygon(vals []float64) *SearchCollectionParams {
+	p.spatial.polygon = vals
+	return p
+}
+
+// Region limits results to collections overlapping any of the polygons, as returned by
+// ReadRegion
+func (p *SearchCollectionParams) Region(polygons [][]Point) *SearchCollectionParams {
+	p.spatial.region = polygons
+	return p
+}
+
+// Limit stops the search after n collections, where 0 is no limit
+func (p *SearchCollectionParams) Limit(n int) *SearchCollectionParams {
+	p.limit = n
+	return p
+}
+
 func (p *SearchCollectionParams) build() (url.Values, error) {
 	query := url.Values{}
 	if p.keyword != "" {
@@ -202,6 +268,9 @@ func (p *SearchCollectionParams) build() (url.Values, error) {
 	for _, name := range p.shortnames {
 		query.Add("short_name", name)
 	}
+	for _, version := range p.versions {
+		query.Add("version", version)
+	}
 	if p.titlePattern != "" {
 		query.Set("options[entry_title][pattern]", "true")
 		query.Set("options[entry_title][ignore_case]", "true")
@@ -213,6 +282,9 @@ func (p *SearchCollectionParams) build() (url.Values, error) {
 	if p.granulesAdded != nil {
 		query.Set("has_granules_revised_at", encodeTimeRange(*p.granulesAdded))
 	}
+	if p.temporal != nil {
+		query.Set("temporal", encodeTimeRange(*p.temporal))
+	}
 	if p.cloudHostedSet {
 		query.Set("cloud_hosted", fmt.Sprintf("%v", p.cloudHosted))
 	}
@@ -229,6 +301,10 @@ func (p *SearchCollectionParams) build() (url.Values, error) {
 		query.Set("options[collection_data_type][ignore_case]", "true")
 		query.Set("collection_data_type", p.dataType)
 	}
+	if err := p.spatial.build(query); err != nil {
+		return query, err
+	}
+	addAttributeParams(query, p.attributes)
 	return query, nil
 }
 
@@ -237,29 +313,65 @@ func (api *CMRSearchAPI) SearchCollections(ctx context.Context, params *SearchCo
 	if err != nil {
 		return ScrollResult[Collection]{}, err
 	}
-	query.Set("page_size", fmt.Sprintf("%v", api.pageSize))
-	url := fmt.Sprintf("%s/collections.umm_json?%s", defaultCMRSearchURL, query.Encode())
+	query.Set("page_size", fmt.Sprintf("%v", api.pageSizeFor(params.limit)))
+	url := fmt.Sprintf("%s/collections.umm_json?%s", api.url, query.Encode())
 
-	zult, err := api.Get(ctx, url)
-	// FIXME: Get never returns an error
+	zult, err := api.scroll(ctx, url, params.limit)
 	if err != nil {
 		return ScrollResult[Collection]{}, err
 	}
-	if zult.Err() != nil {
-		return ScrollResult[Collection]{}, zult.Err()
-	}
 
-	gzult := newScrollResult[Collection]()
+	gzult := newScrollResult[Collection](ctx)
 	gzult.hits = zult.hits
 
 	go func() {
 		defer close(gzult.Ch)
 		for gj := range zult.Ch {
-			gzult.Ch <- newCollectionFromUMM(gj)
+			select {
+			case gzult.Ch <- newCollectionFromUMM(gj):
+			case <-ctx.Done():
+				gzult.setErr(ctx.Err())
+				return
+			}
 		}
+		gzult.setErr(zult.Err())
 	}()
 
 	return gzult, nil
 }
 
+// CountCollections returns the number of collections matching params without retrieving them.
+func (api *CMRSearchAPI) CountCollections(ctx context.Context, params *SearchCollectionParams) (int, error) {
+	query, err := params.build()
+	if err != nil {
+		return 0, err
+	}
+	query.Set("page_size", "0")
+	return api.count(ctx, fmt.Sprintf("%s/collections.umm_json?%s", api.url, query.Encode()))
+}
+
 type CollectionResult = ScrollResult[Collection]
+
+// S3CredentialsURL returns the s3credentials endpoint for the collection with shortname and
+// version, which provides temporary credentials for accessing the collection's granules
+// via direct access.
+func (api *CMRSearchAPI) S3CredentialsURL(ctx context.Context, shortname, version string) (string, error) {
+	params := NewSearchCollectionParams().ShortNames(shortname).Versions(version)
+	zult, err := api.SearchCollections(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	var endpoint string
+	for col := range zult.Ch {
+		if endpoint == "" {
+			endpoint = col["s3_credentials_url"]
+		}
+	}
+	if err := zult.Err(); err != nil {
+		return "", err
+	}
+	if endpoint == "" {
+		return "", fmt.Errorf("no s3 credentials endpoint for collection %s/%s", shortname, version)
+	}
+	return endpoint, nil
+}
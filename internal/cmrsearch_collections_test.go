@@ -26,6 +26,7 @@ func TestSearchCollectionParams(t *testing.T) {
 		UpdatedSince(refTime).
 		GranulesAdded(TimeRange{Start: refTime}).
 		DataType("dt").
+		Timerange(TimeRange{Start: refTime, End: &refTime}).
 		build()
 	require.NoError(t, err)
 
@@ -38,6 +39,7 @@ func TestSearchCollectionParams(t *testing.T) {
 	require.Equal(t, "1970-01-01T00:00:00Z", q.Get("updated_since"))
 	require.Equal(t, "1970-01-01T00:00:00Z,", q.Get("has_granules_revised_at"))
 	require.Equal(t, "dt", q.Get("collection_data_type"))
+	require.Equal(t, "1970-01-01T00:00:00Z,1970-01-01T00:00:00Z", q.Get("temporal"))
 
 	require.Equal(t, false, params.cloudHostedSet)
 	q, err = params.CloudHosted(true).build()
@@ -53,6 +55,28 @@ func TestSearchCollectionParams(t *testing.T) {
 	q, err = params.HasGranules(true).build()
 	require.NoError(t, err)
 	require.Equal(t, "true", q.Get("has_granules"))
+
+	q, err = params.
+		BoundingBox([]float64{1, 2, 3, 4}).
+		Point([]float64{1, 2}).
+		Circle([]float64{1.1, 2.2, 3.3}).
+		Polygon([]float64{1, 2, 3, 4, 5, 6, 1, 2}).
+		build()
+	require.NoError(t, err)
+	require.Equal(t, "1,2,3,4", q.Get("bounding_box"))
+	require.Equal(t, "1,2", q.Get("point"))
+	require.Equal(t, "1.1,2.2,3.3", q.Get("circle"))
+	require.Equal(t, "1,2,3,4,5,6,1,2", q.Get("polygon"))
+
+	q, err = NewSearchCollectionParams().Attributes(AttributeFilter{"int", "H", []string{"8"}}).build()
+	require.NoError(t, err)
+	require.Equal(t, []string{"int,H,8"}, q["attribute[]"])
+
+	square := []Point{{0, 0}, {1, 0}, {1, 1}, {0, 0}}
+	q, err = NewSearchCollectionParams().Region([][]Point{square}).build()
+	require.NoError(t, err)
+	require.Equal(t, []string{"0,0,1,0,1,1,0,0"}, q["polygon[]"])
+	require.Empty(t, q.Get("options[spatial][or]"))
 }
 
 func Test_newCollectionFromUMM(t *testing.T) {
@@ -72,6 +96,7 @@ func Test_newCollectionFromUMM(t *testing.T) {
 	require.Equal(t, "2023-04-12T15:03:44.726Z", col["revision_date"])
 	require.Equal(t, "The VIIRS/SNPP Dark Target Aerosol L2 6-Min Swath 6 km product provides satellite-derived measurements of Aerosol Optical Thickness (AOT) and their properties over land and ocean, and spectral AOT and their size parameters over oceans every 6 minutes, globally.  The Suomi National Polar-orbiting Partnership (SNPP) Visible Infrared Imaging Radiometer Suite (VIIRS) incarnation of the dark target (DT) aerosol product is based on the same DT algorithm that was developed and used to derive products from the Terra and Aqua mission&#8217;s MODIS instruments.  Two separate and distinct DT algorithms exist.  One helps retrieve aerosol information over ocean (dark in visible and longer wavelengths), while the second aids retrievals over vegetated/dark-soiled land (dark in the visible).\r\n\r\nThis orbit-level product (Short-name: AERDT_L2_VIIRS_SNPP) has an at-nadir resolution of 6 km x 6 km, and progressively increases away from nadir given the sensor&#8217;s scanning geometry and Earth&#8217;s curvature.  Viewed differently, this product&#8217;s resolution accommodates 8 x 8 native VIIRS moderate-resolution (M-band) pixels that nominally have ~750 m horizontal pixel size.  Hence, the L2 DT AOT data product incorporates 64 (750 m) pixels over a 6-minute acquisition.\r\n\r\nIn contrast to collection 1 of this product, this collection 1.1 uses bowtie-restored pixels that are used for both cloud masking and for performing some retrievals using the restored pixels as well.\r\n\r\nFor more information consult LAADS product description page at:\r\n\r\nhttps://ladsweb.modaps.eosdis.nasa.gov/missions-and-measurements/products/AERDT_L2_VIIRS_SNPP\r\n\r\nOr, Dark Target aerosol team Page at: \r\nhttps://darktarget.gsfc.nasa.gov/", col["abstract"])
 	require.Equal(t, "SCIENCE_QUALITY", col["data_type"])
+	require.Equal(t, "https://data.laadsdaac.earthdatacloud.nasa.gov/s3credentials", col["s3_credentials_url"])
 	require.Equal(t, "Suomi-NPP/VIIRS", col["instruments"])
 	require.Equal(t, "https://darktarget.gsfc.nasa.gov/pubs\nhttps://darktarget.gsfc.nasa.gov/sites/default/files/DT_Aerosol_UsersGuide_MODIS_VIIRS_v3.pdf\nhttps://darktarget.gsfc.nasa.gov/atbd/overview", col["infourls"])
 }
@@ -28,14 +28,20 @@ type SearchGranuleParams struct {
 	filenames     []string
 	collectionIDs []string
 	nativeIDs     []string
-	boundingBox   []float64
-	point         []float64
-	circle        []float64
-	polygon       []float64
+	spatial       spatialParams
 	versions      []string
 
-	timerangeStart *time.Time
-	timerangeEnd   *time.Time
+	timeranges   []TimeRange
+	season       *Season
+	updatedSince *time.Time
+
+	cloudCover          *FloatRange
+	orbitNumber         *FloatRange
+	equatorCrossingLon  *FloatRange
+	equatorCrossingDate *TimeRange
+	attributes          []AttributeFilter
+	sortKeys            []string
+	limit               int
 }
 
 func NewSearchGranuleParams() *SearchGranuleParams {
@@ -73,31 +79,109 @@ func (p *SearchGranuleParams) NativeIDs(id ...string) *SearchGranuleParams {
 }
 
 func (p *SearchGranuleParams) BoundingBox(vals []float64) *SearchGranuleParams {
-	p.boundingBox = vals
+	p.spatial.boundingBox = vals
 	return p
 }
 
 func (p *SearchGranuleParams) Point(vals []float64) *SearchGranuleParams {
-	p.point = vals
+	p.spatial.point = vals
 	return p
 }
 
 func (p *SearchGranuleParams) Circle(vals []float64) *SearchGranuleParams {
-	p.circle = vals
+	p.spatial.circle = vals
 	return p
 }
 
 func (p *SearchGranuleParams) Polygon(vals []float64) *SearchGranuleParams {
-	p.polygon = vals
+	p.spatial.polygon = vals
+	return p
+}
+
+// Region limits results to granules overlapping any of the polygons, as returned by ReadRegion
+func (p *SearchGranuleParams) Region(polygons [][]Point) *SearchGranuleParams {
+	p.spatial.region = polygons
 	return p
 }
 
 func (p *SearchGranuleParams) Timerange(start time.Time, end *time.Time) *SearchGranuleParams {
-	p.timerangeStart = &start
-	p.timerangeEnd = end
+	p.timeranges = []TimeRange{{Start: start, End: end}}
+	return p
+}
+
+// Timeranges limits results to granules overlapping any of the time ranges
+func (p *SearchGranuleParams) Timeranges(ranges ...TimeRange) *SearchGranuleParams {
+	p.timeranges = ranges
+	return p
+}
+
+// Season limits results to granules within a day of year window in every year of the time
+// ranges. Time ranges must have an end.
+func (p *SearchGranuleParams) Season(season Season) *SearchGranuleParams {
+	p.season = &season
+	return p
+}
+
+// CloudCover limits results to granules with a cloud cover percentage in r
+func (p *SearchGranuleParams) CloudCover(r FloatRange) *SearchGranuleParams {
+	p.cloudCover = &r
+	return p
+}
+
+// OrbitNumber limits results to granules with an orbit number in r
+func (p *SearchGranuleParams) OrbitNumber(r FloatRange) *SearchGranuleParams {
+	p.orbitNumber = &r
+	return p
+}
+
+// EquatorCrossingLongitude limits results to granules with an equator crossing longitude in r.
+// Min may be greater than max for a range that crosses the antimeridian.
+func (p *SearchGranuleParams) EquatorCrossingLongitude(r FloatRange) *SearchGranuleParams {
+	p.equatorCrossingLon = &r
+	return p
+}
+
+// EquatorCrossingDate limits results to granules with an equator crossing date in tr
+func (p *SearchGranuleParams) EquatorCrossingDate(tr TimeRange) *SearchGranuleParams {
+	p.equatorCrossingDate = &tr
+	return p
+}
+
+// SortBy sets the result sort keys, where a key prefixed with - is sorted descending. The
+// default is by descending start date.
+func (p *SearchGranuleParams) SortBy(keys ...string) *SearchGranuleParams {
+	p.sortKeys = keys
+	return p
+}
+
+// Limit stops the search after n granules, where 0 is no limit. A granule with more than one
+// file still provides a result for each file.
+func (p *SearchGranuleParams) Limit(n int) *SearchGranuleParams {
+	p.limit = n
+	return p
+}
+
+// Attributes limits results to granules matching all of the additional attribute filters
+func (p *SearchGranuleParams) Attributes(filters ...AttributeFilter) *SearchGranuleParams {
+	p.attributes = filters
+	return p
+}
+
+// UpdatedSince limits results to granules with a revision date on or after t
+func (p *SearchGranuleParams) UpdatedSince(t time.Time) *SearchGranuleParams {
+	p.updatedSince = &t
 	return p
 }
 
+// Encode returns the encoded query for these params, suitable for identifying a query.
+func (p *SearchGranuleParams) Encode() (string, error) {
+	query, err := p.build()
+	if err != nil {
+		return "", err
+	}
+	return query.Encode(), nil
+}
+
 func (p *SearchGranuleParams) build() (url.Values, error) {
 	query := url.Values{}
 	if p.daynight != "" {
@@ -125,61 +209,102 @@ func (p *SearchGranuleParams) build() (url.Values, error) {
 			query.Add("native_id", name)
 		}
 	}
-	if p.timerangeStart != nil {
-		s := p.timerangeStart.Format(time.RFC3339) + ","
-		if p.timerangeEnd != nil {
-			s += p.timerangeEnd.Format(time.RFC3339)
-		}
-		query.Set("temporal", s)
+	if p.season != nil && len(p.timeranges) == 0 {
+		return query, fmt.Errorf("season requires a time range")
 	}
-	if len(p.polygon) > 0 {
-		if len(p.polygon)%2 != 0 {
-			return query, fmt.Errorf("number of polygon points must be divisible by 2")
+	temporal := []string{}
+	for _, tr := range p.timeranges {
+		s := tr.Start.Format(time.RFC3339) + ","
+		if tr.End != nil {
+			s += tr.End.Format(time.RFC3339)
 		}
-		query.Set("polygon", joinFloats(p.polygon))
-	}
-	if len(p.circle) != 0 {
-		if len(p.circle) != 3 {
-			return query, fmt.Errorf("wrong number of values for circle")
+		if p.season != nil {
+			if tr.End == nil {
+				return query, fmt.Errorf("season requires time ranges with an end")
+			}
+			s += fmt.Sprintf(",%d,%d", p.season.StartDay, p.season.EndDay)
 		}
-		query.Set("circle", joinFloats(p.circle))
+		temporal = append(temporal, s)
 	}
-	if len(p.boundingBox) != 0 {
-		if len(p.boundingBox) != 4 {
-			return query, fmt.Errorf("wrong number of values for bounding box")
+	switch len(temporal) {
+	case 0:
+	case 1:
+		query.Set("temporal", temporal[0])
+	default:
+		for _, s := range temporal {
+			query.Add("temporal[]", s)
 		}
-		query.Set("bounding_box", joinFloats(p.boundingBox))
+		query.Set("options[temporal][or]", "true")
+	}
+	if p.updatedSince != nil {
+		query.Set("updated_since", p.updatedSince.UTC().Format(time.RFC3339))
+	}
+	if p.cloudCover != nil {
+		query.Set("cloud_cover", p.cloudCover.String())
 	}
-	if len(p.point) != 0 {
-		if len(p.point) != 4 {
-			return query, fmt.Errorf("wrong number of values for point")
+	if p.orbitNumber != nil {
+		query.Set("orbit_number", p.orbitNumber.String())
+	}
+	if p.equatorCrossingLon != nil {
+		r := p.equatorCrossingLon
+		if r.Min == nil || r.Max == nil {
+			return query, fmt.Errorf("equator crossing longitude requires a min and max")
 		}
-		query.Set("point", joinFloats(p.point))
+		query.Set("equator_crossing_longitude", fmt.Sprintf("%v,%v", *r.Min, *r.Max))
+	}
+	if p.equatorCrossingDate != nil {
+		query.Set("equator_crossing_date", encodeTimeRange(*p.equatorCrossingDate))
+	}
+	addAttributeParams(query, p.attributes)
+	if err := p.spatial.build(query); err != nil {
+		return query, err
 	}
 	if len(p.versions) > 0 {
 		for _, version := range p.versions {
 			query.Add("version", version)
 		}
 	}
-	query.Set("sort_key", "-start_date")
+	switch len(p.sortKeys) {
+	case 0:
+		query.Set("sort_key", "-start_date")
+	case 1:
+		query.Set("sort_key", p.sortKeys[0])
+	default:
+		for _, key := range p.sortKeys {
+			query.Add("sort_key[]", key)
+		}
+	}
 	return query, nil
 }
 
 type Granule struct {
-	Name          string            `json:"name"`
-	Size          string            `json:"size"`
-	Checksum      string            `json:"checksum"`
-	ChecksumAlg   string            `json:"checksum_alg"`
-	GetDataURL    string            `json:"download_url"`
-	GetDataDAURL  string            `json:"download_direct_url"`
-	NativeID      string            `json:"native_id"`
-	RevisionID    string            `json:"revision_id"`
-	ConceptID     string            `json:"concept_id"`
-	Collection    string            `json:"collection"`
-	DayNightFlag  string            `json:"daynight"`
-	TimeRange     []string          `json:"timerange"`
-	BoundingBox   []string          `json:"boundingbox"`
-	ProviderDates map[string]string `json:"provider_dates"`
+	Name      string `json:"name"`
+	Size      string `json:"size"`
+	SizeBytes int64  `json:"size_bytes"`
+	// SizeBytes is exact, i.e., from SizeInBytes rather than Size and SizeUnit
+	SizeExact    bool     `json:"-"`
+	Checksum     string   `json:"checksum"`
+	ChecksumAlg  string   `json:"checksum_alg"`
+	GetDataURL   string   `json:"download_url"`
+	GetDataDAURL string   `json:"download_direct_url"`
+	NativeID     string   `json:"native_id"`
+	RevisionID   string   `json:"revision_id"`
+	RevisionDate string   `json:"revision_date"`
+	ConceptID    string   `json:"concept_id"`
+	Collection   string   `json:"collection"`
+	DayNightFlag string   `json:"daynight"`
+	TimeRange    []string `json:"timerange"`
+	// Polygon boundaries as comma separated lon,lat pairs
+	BoundingBox []string `json:"boundingbox"`
+	Geometry    Geometry `json:"geometry"`
+	// Cloud cover percentage, if available
+	CloudCover                *float64  `json:"cloud_cover"`
+	OrbitNumbers              []int     `json:"orbit_number"`
+	EquatorCrossingLongitudes []float64 `json:"equator_crossing_longitude"`
+	EquatorCrossingDates      []string  `json:"equator_crossing_date"`
+	// Additional attribute values by name
+	Attributes    map[string][]string `json:"attributes"`
+	ProviderDates map[string]string   `json:"provider_dates"`
 }
 
 func findDownloadURLs(zult *gjson.Result, directAccess bool) map[string]string {
@@ -207,6 +332,7 @@ func newGranulesFromUMM(zult gjson.Result) []Granule {
 		gran.ConceptID = zult.Get("meta.concept-id").String()
 		gran.NativeID = zult.Get("meta.native-id").String()
 		gran.RevisionID = zult.Get("meta.revision-id").String()
+		gran.RevisionDate = zult.Get("meta.revision-date").String()
 		col := zult.Get("umm.CollectionReference")
 		if col.Exists() {
 			gran.Collection = fmt.Sprintf(
@@ -221,16 +347,24 @@ func newGranulesFromUMM(zult gjson.Result) []Granule {
 			zult.Get("umm.TemporalExtent.RangeDateTime.BeginningDateTime").String(),
 			zult.Get("umm.TemporalExtent.RangeDateTime.EndingDateTime").String(),
 		}
+		gran.Geometry = decodeGeometry(zult.Get("umm.SpatialExtent.HorizontalSpatialDomain.Geometry"))
 		gran.BoundingBox = []string{}
-		for _, polygon := range zult.Get("umm.SpatialExtent.HorizontalSpatialDomain.Geometry.GPolygons").Array() {
+		for _, polygon := range gran.Geometry.GPolygons {
 			points := []string{}
-			for _, point := range polygon.Get("Boundary.Points").Array() {
-				points = append(points, fmt.Sprintf("%v", point.Get("Longitude").Float()))
-				points = append(points, fmt.Sprintf("%v", point.Get("Latitude").Float()))
+			for _, point := range polygon.Boundary {
+				points = append(points, fmt.Sprintf("%v", point.Lon))
+				points = append(points, fmt.Sprintf("%v", point.Lat))
 			}
 			gran.BoundingBox = append(gran.BoundingBox, strings.Join(points, ","))
 		}
 
+		if cc := zult.Get("umm.CloudCover"); cc.Exists() {
+			v := cc.Float()
+			gran.CloudCover = &v
+		}
+		decodeOrbitDomains(&gran, zult.Get("umm.OrbitCalculatedSpatialDomains"))
+		gran.Attributes = decodeGranuleAttributes(zult.Get("umm.AdditionalAttributes"))
+
 		gran.ProviderDates = map[string]string{}
 		for _, dt := range zult.Get("umm.ProviderDates").Array() {
 			gran.ProviderDates[dt.Get("Type").String()] = dt.Get("Date").String()
@@ -241,20 +375,44 @@ func newGranulesFromUMM(zult gjson.Result) []Granule {
 	return granules
 }
 
+// decodeOrbitDomains sets the orbit output fields from UMM-G OrbitCalculatedSpatialDomains.
+// Domains with a range of orbits contribute the begin and end orbit numbers.
+func decodeOrbitDomains(gran *Granule, domains gjson.Result) {
+	gran.OrbitNumbers = []int{}
+	gran.EquatorCrossingLongitudes = []float64{}
+	gran.EquatorCrossingDates = []string{}
+	for _, dom := range domains.Array() {
+		if v := dom.Get("OrbitNumber"); v.Exists() {
+			gran.OrbitNumbers = append(gran.OrbitNumbers, int(v.Int()))
+		} else if begin := dom.Get("BeginOrbitNumber"); begin.Exists() {
+			gran.OrbitNumbers = append(gran.OrbitNumbers, int(begin.Int()))
+			if end := dom.Get("EndOrbitNumber"); end.Exists() && end.Int() != begin.Int() {
+				gran.OrbitNumbers = append(gran.OrbitNumbers, int(end.Int()))
+			}
+		}
+		if v := dom.Get("EquatorCrossingLongitude"); v.Exists() {
+			gran.EquatorCrossingLongitudes = append(gran.EquatorCrossingLongitudes, v.Float())
+		}
+		if v := dom.Get("EquatorCrossingDateTime"); v.Exists() {
+			gran.EquatorCrossingDates = append(gran.EquatorCrossingDates, v.String())
+		}
+	}
+}
+
 func (api *CMRSearchAPI) SearchGranules(ctx context.Context, params *SearchGranuleParams) (ScrollResult[Granule], error) {
 	query, err := params.build()
 	if err != nil {
 		return ScrollResult[Granule]{}, err
 	}
-	query.Set("page_size", fmt.Sprintf("%v", api.pageSize))
-	url := fmt.Sprintf("%s/granules.umm_json?%s", defaultCMRSearchURL, query.Encode())
+	query.Set("page_size", fmt.Sprintf("%v", api.pageSizeFor(params.limit)))
+	url := fmt.Sprintf("%s/granules.umm_json?%s", api.url, query.Encode())
 
-	zult, err := api.Get(ctx, url)
+	zult, err := api.scroll(ctx, url, params.limit)
 	if err != nil {
 		return ScrollResult[Granule]{}, err
 	}
 
-	gzult := newScrollResult[Granule]()
+	gzult := newScrollResult[Granule](ctx)
 	// hits is set before Get returns
 	gzult.hits = zult.hits
 
@@ -262,18 +420,36 @@ func (api *CMRSearchAPI) SearchGranules(ctx context.Context, params *SearchGranu
 		defer close(gzult.Ch)
 		for gj := range zult.Ch {
 			for _, gran := range newGranulesFromUMM(gj) {
-				gzult.Ch <- gran
+				select {
+				case gzult.Ch <- gran:
+				case <-ctx.Done():
+					gzult.setErr(ctx.Err())
+					return
+				}
 			}
 		}
+		gzult.setErr(zult.Err())
 	}()
 
 	return gzult, nil
 }
 
+// CountGranules returns the number of granules matching params without retrieving them.
+func (api *CMRSearchAPI) CountGranules(ctx context.Context, params *SearchGranuleParams) (int, error) {
+	query, err := params.build()
+	if err != nil {
+		return 0, err
+	}
+	query.Set("page_size", "0")
+	return api.count(ctx, fmt.Sprintf("%s/granules.umm_json?%s", api.url, query.Encode()))
+}
+
 type GranuleResult = ScrollResult[Granule]
 
 type archiveInfo struct {
 	Size        string
+	SizeBytes   int64
+	SizeExact   bool
 	Checksum    string
 	ChecksumAlg string
 }
@@ -287,6 +463,25 @@ func (a *archiveInfo) String() string {
 
 var _ fmt.Stringer = (*archiveInfo)(nil)
 
+var sizeUnitMultipliers = map[string]float64{
+	"":   1,
+	"KB": 1e3,
+	"MB": 1e6,
+	"GB": 1e9,
+	"TB": 1e12,
+	"PB": 1e15,
+}
+
+// sizeToBytes converts a UMM-G Size and SizeUnit to an approximate number of bytes, or 0 if
+// the unit is not known.
+func sizeToBytes(size float64, unit string) int64 {
+	mult, ok := sizeUnitMultipliers[strings.ToUpper(strings.TrimSpace(unit))]
+	if !ok {
+		return 0
+	}
+	return int64(size * mult)
+}
+
 // decodeArchiveInfo parses Size, Checksum and ChecksumAlg out of an array of archive info, iff
 // the archive info has a name and it matches the download url name.
 //
@@ -317,8 +512,11 @@ func decodeArchiveInfo(docs []gjson.Result) map[string]archiveInfo {
 			size := ar.Get("Size").Int()
 			if sizeInBytes != 0 {
 				info.Size = ByteCountSI(sizeInBytes)
+				info.SizeBytes = sizeInBytes
+				info.SizeExact = true
 			} else if size != 0 {
 				info.Size = strings.TrimSpace(fmt.Sprintf("%v %v", size, ar.Get("SizeUnit").String()))
+				info.SizeBytes = sizeToBytes(ar.Get("Size").Float(), ar.Get("SizeUnit").String())
 			}
 		}
 
@@ -369,6 +567,8 @@ func findGranules(item gjson.Result) []Granule {
 		if info, ok := archiveInfos[name]; ok {
 			log.Debug("archive info for name=%s: %s", name, info.String())
 			gran.Size = info.Size
+			gran.SizeBytes = info.SizeBytes
+			gran.SizeExact = info.SizeExact
 			gran.Checksum = info.Checksum
 			gran.ChecksumAlg = info.ChecksumAlg
 			files[name] = gran
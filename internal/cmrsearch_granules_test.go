@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"testing"
 	"time"
@@ -23,7 +24,7 @@ func TestGranuleSearchParams(t *testing.T) {
 		Collections("c1", "c2").
 		NativeIDs("n1", "n2").
 		BoundingBox([]float64{1, 2, 3, 4}).
-		Point([]float64{1, 2, 3, 4}).
+		Point([]float64{1, 2}).
 		Circle([]float64{1.1, 2.2, 3.3}).
 		Polygon([]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 0}).
 		Timerange(refTime, nil).
@@ -36,14 +37,79 @@ func TestGranuleSearchParams(t *testing.T) {
 	require.Equal(t, []string{"c1", "c2"}, q["collection_concept_id"])
 	require.Equal(t, []string{"n1", "n2"}, q["native_id"])
 	require.Equal(t, "1,2,3,4", q.Get("bounding_box"))
-	require.Equal(t, "1,2,3,4", q.Get("point"))
+	require.Equal(t, "1,2", q.Get("point"))
 	require.Equal(t, "1.1,2.2,3.3", q.Get("circle"))
 	require.Equal(t, "1,2,3,4,5,6,7,8,9,0", q.Get("polygon"))
 
 	require.Equal(t, "1970-01-01T00:00:00Z,", q.Get("temporal"))
+	require.Equal(t, "-start_date", q.Get("sort_key"), "default sort")
 	q, err = params.Timerange(refTime, &refTime).build()
 	require.NoError(t, err)
 	require.Equal(t, "1970-01-01T00:00:00Z,1970-01-01T00:00:00Z", q.Get("temporal"))
+
+	require.Empty(t, q.Get("updated_since"))
+	q, err = params.UpdatedSince(refTime.Add(time.Hour)).build()
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T01:00:00Z", q.Get("updated_since"))
+
+	end := refTime.Add(time.Hour)
+	q, err = NewSearchGranuleParams().Timeranges(
+		TimeRange{Start: refTime, End: &end},
+		TimeRange{Start: end},
+	).build()
+	require.NoError(t, err)
+	require.Empty(t, q.Get("temporal"))
+	require.Equal(t, []string{"1970-01-01T00:00:00Z,1970-01-01T01:00:00Z", "1970-01-01T01:00:00Z,"}, q["temporal[]"])
+	require.Equal(t, "true", q.Get("options[temporal][or]"))
+
+	q, err = NewSearchGranuleParams().
+		Timerange(refTime, &end).
+		Season(Season{StartDay: 182, EndDay: 212}).
+		build()
+	require.NoError(t, err)
+	require.Equal(t, "1970-01-01T00:00:00Z,1970-01-01T01:00:00Z,182,212", q.Get("temporal"))
+
+	_, err = NewSearchGranuleParams().Timerange(refTime, nil).Season(Season{1, 2}).build()
+	require.Error(t, err, "season requires a range end")
+	_, err = NewSearchGranuleParams().Season(Season{1, 2}).build()
+	require.Error(t, err, "season requires a range")
+
+	lo, hi := 10.0, 20.0
+	q, err = NewSearchGranuleParams().
+		CloudCover(FloatRange{Max: &hi}).
+		OrbitNumber(FloatRange{Min: &lo, Max: &lo}).
+		EquatorCrossingLongitude(FloatRange{Min: &hi, Max: &lo}).
+		EquatorCrossingDate(TimeRange{Start: refTime}).
+		build()
+	require.NoError(t, err)
+	require.Equal(t, ",20", q.Get("cloud_cover"))
+	require.Equal(t, "10", q.Get("orbit_number"))
+	require.Equal(t, "20,10", q.Get("equator_crossing_longitude"), "antimeridian crossing range")
+	require.Equal(t, "1970-01-01T00:00:00Z,", q.Get("equator_crossing_date"))
+
+	q, err = NewSearchGranuleParams().SortBy("start_date").build()
+	require.NoError(t, err)
+	require.Equal(t, "start_date", q.Get("sort_key"))
+	q, err = NewSearchGranuleParams().SortBy("short_name", "-start_date").build()
+	require.NoError(t, err)
+	require.Empty(t, q.Get("sort_key"))
+	require.Equal(t, []string{"short_name", "-start_date"}, q["sort_key[]"])
+
+	q, err = NewSearchGranuleParams().Attributes(
+		AttributeFilter{"int", "H", []string{"8"}},
+		AttributeFilter{"float", "F", []string{"", "1.5"}},
+	).build()
+	require.NoError(t, err)
+	require.Equal(t, []string{"int,H,8", "float,F,,1.5"}, q["attribute[]"])
+
+	_, err = NewSearchGranuleParams().EquatorCrossingLongitude(FloatRange{Min: &lo}).build()
+	require.Error(t, err, "equator crossing longitude must not be open")
+
+	square := []Point{{0, 0}, {1, 0}, {1, 1}, {0, 0}}
+	q, err = NewSearchGranuleParams().Region([][]Point{square, square}).build()
+	require.NoError(t, err)
+	require.Equal(t, []string{"0,0,1,0,1,1,0,0", "0,0,1,0,1,1,0,0"}, q["polygon[]"])
+	require.Equal(t, "true", q.Get("options[spatial][or]"))
 }
 
 func Test_newGranuleFromUMM(t *testing.T) {
@@ -129,12 +195,14 @@ func Test_newGranuleFromUMM(t *testing.T) {
 
 		require.Equal(t, "AERDT_L2_VIIRS_SNPP.A2023117.1654.011.nrt.nc", gran.Name)
 		require.Equal(t, "7.0 MB", gran.Size)
+		require.Greater(t, gran.SizeBytes, int64(7e6))
 		require.Equal(t, "3967c4c9d5768e4eff7e1b508b9011f2", gran.Checksum)
 		require.Equal(t, "MD5", gran.ChecksumAlg)
 		require.Equal(t, "https://sips-data.ssec.wisc.edu/nrt/47503027/AERDT_L2_VIIRS_SNPP.A2023117.1654.011.nrt.nc", gran.GetDataURL)
 		require.Equal(t, "", gran.GetDataDAURL)
 		require.Equal(t, "ASIPS:AERDT_L2_VIIRS_SNPP_NRT:1682614440", gran.NativeID)
 		require.Equal(t, "1", gran.RevisionID)
+		require.Equal(t, "2023-04-27T19:29:07.960Z", gran.RevisionDate)
 		require.Equal(t, "G2669133699-ASIPS", gran.ConceptID)
 		require.Equal(t, "AERDT_L2_VIIRS_SNPP_NRT/1.1", gran.Collection)
 		require.Equal(t, "Day", gran.DayNightFlag)
@@ -142,9 +210,38 @@ func Test_newGranuleFromUMM(t *testing.T) {
 		require.Equal(t, []string{
 			"-131.310653687,66.963340759,-92.430793762,55.710681915,-37.703670502,63.907997131,-4.32655859,82.950004578,-131.310653687,66.963340759",
 		}, gran.BoundingBox)
+		require.Nil(t, gran.CloudCover)
+		require.Empty(t, gran.OrbitNumbers)
+		require.Len(t, gran.Geometry.GPolygons, 1)
+		require.Len(t, gran.Geometry.GPolygons[0].Boundary, 5)
+		require.Equal(t, Point{Lon: -131.310653687, Lat: 66.963340759}, gran.Geometry.GPolygons[0].Boundary[0])
 	})
 }
 
+func Test_decodeOrbitFields(t *testing.T) {
+	doc := `{
+		"meta": {"concept-id": "G1-X"},
+		"umm": {
+			"CloudCover": 12.5,
+			"RelatedUrls": [{"URL": "https://host/a.nc", "Type": "GET DATA"}],
+			"OrbitCalculatedSpatialDomains": [
+				{"OrbitNumber": 100, "EquatorCrossingLongitude": -45.5, "EquatorCrossingDateTime": "2023-01-01T00:00:00Z"},
+				{"BeginOrbitNumber": 101, "EndOrbitNumber": 102}
+			]
+		}
+	}`
+	require.True(t, gjson.Valid(doc))
+
+	grans := newGranulesFromUMM(gjson.Parse(doc))
+	require.Len(t, grans, 1)
+	gran := grans[0]
+	require.NotNil(t, gran.CloudCover)
+	require.Equal(t, 12.5, *gran.CloudCover)
+	require.Equal(t, []int{100, 101, 102}, gran.OrbitNumbers)
+	require.Equal(t, []float64{-45.5}, gran.EquatorCrossingLongitudes)
+	require.Equal(t, []string{"2023-01-01T00:00:00Z"}, gran.EquatorCrossingDates)
+}
+
 func TestSearchGranules(t *testing.T) {
 	newServer := func(t *testing.T, body string, status int, hits string) func() {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -221,15 +318,21 @@ func TestDecodeArchiveInfo(t *testing.T) {
         "Name": "CAL_LID_L1-Standard-V4-51.2016-08-31T23-21-32ZD.hdf.met",
         "Size": 8.0,
         "SizeUnit": "KB"
+      },
+      {
+        "Name": "exact.hdf",
+        "SizeInBytes": 1234
       }
     ]
   `)
 	infos := decodeArchiveInfo(ar.Array())
 
-	require.Len(t, infos, 2)
+	require.Len(t, infos, 3)
 
 	info := infos["CAL_LID_L1-Standard-V4-51.2016-08-31T23-21-32ZD.hdf"]
 	require.Equal(t, "999 MB", info.Size)
+	require.Equal(t, int64(999e6), info.SizeBytes)
+	require.False(t, info.SizeExact)
 	require.Equal(t, "MD5", info.ChecksumAlg)
 	require.Equal(t, "ffffffffffffffffffffffffffffffff", info.Checksum)
 
@@ -237,4 +340,25 @@ func TestDecodeArchiveInfo(t *testing.T) {
 	require.Equal(t, "8 KB", info.Size)
 	require.Equal(t, "MD5", info.ChecksumAlg)
 	require.Equal(t, "3e84cf5f8ffb0e97627ff9462cec8534", info.Checksum)
+
+	info = infos["exact.hdf"]
+	require.Equal(t, int64(1234), info.SizeBytes)
+	require.True(t, info.SizeExact)
+}
+
+func TestCountGranules(t *testing.T) {
+	var query url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("cmr-hits", "1880")
+		_, _ = w.Write([]byte(`{"hits": 1880, "items": []}`))
+	}))
+	defer ts.Close()
+
+	api := NewCMRSearchAPI().WithURL(ts.URL)
+	hits, err := api.CountGranules(context.Background(), NewSearchGranuleParams().Collections("C1-XXX").Limit(10))
+	require.NoError(t, err)
+	require.Equal(t, 1880, hits)
+	require.Equal(t, "0", query.Get("page_size"))
+	require.Equal(t, "C1-XXX", query.Get("collection_concept_id"))
 }
@@ -20,26 +20,33 @@ func (api *CMRSearchAPI) SearchFacets(ctx context.Context, val string, types []s
 	for _, typ := range types {
 		query.Add("type[]", typ)
 	}
-	url := fmt.Sprintf("%s/autocomplete?%s", defaultCMRSearchURL, query.Encode())
+	url := fmt.Sprintf("%s/autocomplete?%s", api.url, query.Encode())
 
 	zult, err := api.Get(ctx, url)
 	if err != nil {
-		return ScrollResult[Facet]{}, nil
+		return ScrollResult[Facet]{}, err
 	}
 
-	gzult := newScrollResult[Facet]()
+	gzult := newScrollResult[Facet](ctx)
 	gzult.hits = zult.hits
 
 	go func() {
 		defer close(gzult.Ch)
 		for gj := range zult.Ch {
-			gzult.Ch <- Facet{
+			facet := Facet{
 				Score:  gj.Get("score").Float(),
 				Type:   gj.Get("type").String(),
 				Fields: gj.Get("fields").String(),
 				Value:  gj.Get("value").String(),
 			}
+			select {
+			case gzult.Ch <- facet:
+			case <-ctx.Done():
+				gzult.setErr(ctx.Err())
+				return
+			}
 		}
+		gzult.setErr(zult.Err())
 	}()
 
 	return gzult, nil
@@ -83,16 +83,9 @@ func TestSearchFacets(t *testing.T) {
 		cleanup := newServer(t, "{}", http.StatusBadRequest, "1")
 		defer cleanup()
 
-		zult := doGet(t, "xxx", []string{"t1", "t2"})
-		require.NoError(t, zult.Err())
-		require.Equal(t, 0, zult.Hits())
-
-		facets := []Facet{}
-		for f := range zult.Ch {
-			facets = append(facets, f)
-		}
+		_, err := NewCMRSearchAPI().SearchFacets(context.Background(), "xxx", []string{"t1", "t2"})
 
-		require.NoError(t, zult.Err())
-		require.Len(t, facets, 0)
+		var cmrErr *CMRError
+		require.ErrorAs(t, err, &cmrErr, "first page errors should be returned")
 	})
 }
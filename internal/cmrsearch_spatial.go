@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// spatialParams are the spatial search params shared by collection and granule searches
+type spatialParams struct {
+	boundingBox []float64
+	point       []float64
+	circle      []float64
+	polygon     []float64
+	region      [][]Point
+}
+
+func (p *spatialParams) build(query url.Values) error {
+	if len(p.polygon) > 0 {
+		if len(p.polygon)%2 != 0 {
+			return fmt.Errorf("number of polygon points must be divisible by 2")
+		}
+		query.Set("polygon", joinFloats(p.polygon))
+	}
+	addRegionParams(query, p.region)
+	if len(p.circle) != 0 {
+		if len(p.circle) != 3 {
+			return fmt.Errorf("wrong number of values for circle")
+		}
+		query.Set("circle", joinFloats(p.circle))
+	}
+	if len(p.boundingBox) != 0 {
+		if len(p.boundingBox) != 4 {
+			return fmt.Errorf("wrong number of values for bounding box")
+		}
+		query.Set("bounding_box", joinFloats(p.boundingBox))
+	}
+	if len(p.point) != 0 {
+		if len(p.point) != 2 {
+			return fmt.Errorf("wrong number of values for point")
+		}
+		query.Set("point", joinFloats(p.point))
+	}
+	return nil
+}
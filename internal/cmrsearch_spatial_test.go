@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpatialParams(t *testing.T) {
+	errCases := map[string]spatialParams{
+		"odd polygon":  {polygon: []float64{1, 2, 3}},
+		"circle":       {circle: []float64{1, 2}},
+		"bounding box": {boundingBox: []float64{1, 2, 3}},
+		"point":        {point: []float64{1, 2, 3, 4}},
+	}
+	for name, params := range errCases {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, params.build(url.Values{}))
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		query := url.Values{}
+		require.NoError(t, (&spatialParams{}).build(query))
+		require.Empty(t, query)
+	})
+}
@@ -27,17 +27,15 @@ func TestCMRSearchAPI(t *testing.T) {
 		return ts, url
 	}
 
-	doGet := func(t *testing.T, url string) ScrollResult[gjson.Result] {
+	doGet := func(t *testing.T, url string) (ScrollResult[gjson.Result], error) {
 		t.Helper()
 
 		api := NewCMRSearchAPI()
 		// make sure we're not waiting long
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-		defer cancel()
-		zult, err := api.Get(ctx, url)
-		require.NoError(t, err)
-
-		return zult
+		// results are received after returning and are not sent once ctx is done
+		t.Cleanup(cancel)
+		return api.Get(ctx, url)
 	}
 
 	t.Run("get", func(t *testing.T) {
@@ -52,12 +50,10 @@ func TestCMRSearchAPI(t *testing.T) {
 			svr, url := newServer(t, body, http.StatusBadRequest, "")
 			defer svr.Close()
 
-			zult := doGet(t, url)
-			_, more := <-zult.Ch
-			require.False(t, more, "expected channel to be closed")
+			_, err := doGet(t, url)
 
 			var cmrErr *CMRError
-			require.ErrorAs(t, zult.Err(), &cmrErr, "Expected CMRError")
+			require.ErrorAs(t, err, &cmrErr, "Expected CMRError")
 			require.Contains(t, cmrErr.Error(), "Your request is borked")
 		})
 
@@ -65,9 +61,9 @@ func TestCMRSearchAPI(t *testing.T) {
 			svr, url := newServer(t, "", http.StatusBadRequest, "a")
 			defer svr.Close()
 
-			zult := doGet(t, url)
+			_, err := doGet(t, url)
 
-			require.Error(t, zult.Err(), "expected error for bad hits header")
+			require.Error(t, err, "expected error for bad hits header")
 		})
 
 		t.Run("success", func(t *testing.T) {
@@ -76,9 +72,10 @@ func TestCMRSearchAPI(t *testing.T) {
 				svr, url := newServer(t, body, http.StatusOK, "1")
 				defer svr.Close()
 
-				zult := doGet(t, url)
+				zult, err := doGet(t, url)
+				require.NoError(t, err)
 
-				err := zult.Err()
+				err = zult.Err()
 				require.NoError(t, err, "expected no error for valid body: %#v", err)
 
 				results := []gjson.Result{}
@@ -94,7 +91,8 @@ func TestCMRSearchAPI(t *testing.T) {
 				svr, url := newServer(t, body, http.StatusOK, "1")
 				defer svr.Close()
 
-				zult := doGet(t, url)
+				zult, err := doGet(t, url)
+				require.NoError(t, err)
 
 				require.NoError(t, zult.Err(), "expected no error for valid body")
 
@@ -107,4 +105,206 @@ func TestCMRSearchAPI(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("retry", func(t *testing.T) {
+		newPagingServer := func(t *testing.T, status int) (*httptest.Server, *[]string) {
+			searchAfters := &[]string{}
+			failed := false
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				searchAfter := r.Header.Get("cmr-search-after")
+				*searchAfters = append(*searchAfters, searchAfter)
+				w.Header().Set("cmr-hits", "2")
+				switch {
+				case searchAfter == "":
+					w.Header().Set("cmr-search-after", "page2")
+					_, _ = w.Write([]byte(`{"items": [1]}`))
+				case !failed:
+					// fail the second page once
+					failed = true
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(status)
+				default:
+					_, _ = w.Write([]byte(`{"items": [2]}`))
+				}
+			}))
+			return ts, searchAfters
+		}
+
+		t.Run("retryable status", func(t *testing.T) {
+			svr, searchAfters := newPagingServer(t, http.StatusServiceUnavailable)
+			defer svr.Close()
+
+			api := NewCMRSearchAPI().WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+			zult, err := api.Get(context.Background(), svr.URL)
+			require.NoError(t, err)
+
+			results := []int64{}
+			for r := range zult.Ch {
+				results = append(results, r.Int())
+			}
+			require.NoError(t, zult.Err())
+			require.Equal(t, []int64{1, 2}, results, "no items should be skipped or duplicated")
+			require.Equal(t, []string{"", "page2", "page2"}, *searchAfters)
+		})
+
+		t.Run("permanent status", func(t *testing.T) {
+			svr, searchAfters := newPagingServer(t, http.StatusBadRequest)
+			defer svr.Close()
+
+			api := NewCMRSearchAPI().WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+			zult, err := api.Get(context.Background(), svr.URL)
+			require.NoError(t, err)
+
+			for range zult.Ch {
+			}
+			var cmrErr *CMRError
+			require.ErrorAs(t, zult.Err(), &cmrErr)
+			require.Len(t, *searchAfters, 2, "permanent errors should not be retried")
+		})
+	})
+}
+
+func TestScrollResultFilter(t *testing.T) {
+	zult := newScrollResult[Granule](context.Background())
+	zult.hits = 3
+	go func() {
+		defer close(zult.Ch)
+		for _, name := range []string{"a", "b", "c"} {
+			zult.Ch <- Granule{Name: name}
+		}
+		zult.setErr(fmt.Errorf("boom"))
+	}()
+
+	filtered := zult.Filter(func(g Granule) bool { return g.Name != "b" })
+	names := []string{}
+	for gran := range filtered.Ch {
+		names = append(names, gran.Name)
+	}
+	require.Equal(t, []string{"a", "c"}, names)
+	require.Equal(t, 3, filtered.Hits())
+	require.Error(t, filtered.Err(), "expected error to be shared with the source result")
+
+	t.Run("cancel stops filtering", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		zult := newScrollResult[Granule](ctx)
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			// ignores ctx so only the filter can stop
+			for {
+				select {
+				case zult.Ch <- Granule{Name: "a"}:
+				case <-stop:
+					return
+				}
+			}
+		}()
+
+		filtered := zult.Filter(func(Granule) bool { return true })
+		<-filtered.Ch
+		cancel()
+
+		// the filter must close the channel without the receiver draining it
+		require.Eventually(t, func() bool {
+			select {
+			case _, ok := <-filtered.Ch:
+				return !ok
+			default:
+				return false
+			}
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func TestCMRSearchAPIScrollLimit(t *testing.T) {
+	newPagingServer := func(t *testing.T) (*httptest.Server, *[]string) {
+		searchAfters := &[]string{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			searchAfter := r.Header.Get("cmr-search-after")
+			*searchAfters = append(*searchAfters, searchAfter)
+			w.Header().Set("cmr-hits", "6")
+			switch searchAfter {
+			case "":
+				w.Header().Set("cmr-search-after", "page2")
+				_, _ = w.Write([]byte(`{"items": [1, 2]}`))
+			case "page2":
+				w.Header().Set("cmr-search-after", "page3")
+				_, _ = w.Write([]byte(`{"items": [3, 4]}`))
+			default:
+				_, _ = w.Write([]byte(`{"items": [5, 6]}`))
+			}
+		}))
+		return ts, searchAfters
+	}
+
+	t.Run("stops scrolling at limit", func(t *testing.T) {
+		svr, searchAfters := newPagingServer(t)
+		defer svr.Close()
+
+		zult, err := NewCMRSearchAPI().scroll(context.Background(), svr.URL, 3)
+		require.NoError(t, err)
+		require.Equal(t, 3, zult.Hits(), "hits should be capped at the limit")
+
+		results := []int64{}
+		for r := range zult.Ch {
+			results = append(results, r.Int())
+		}
+		require.NoError(t, zult.Err())
+		require.Equal(t, []int64{1, 2, 3}, results)
+		require.Equal(t, []string{"", "page2"}, *searchAfters, "pages past the limit should not be requested")
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		svr, _ := newPagingServer(t)
+		defer svr.Close()
+
+		zult, err := NewCMRSearchAPI().scroll(context.Background(), svr.URL, 0)
+		require.NoError(t, err)
+		require.Equal(t, 6, zult.Hits())
+
+		results := []int64{}
+		for r := range zult.Ch {
+			results = append(results, r.Int())
+		}
+		require.Equal(t, []int64{1, 2, 3, 4, 5, 6}, results)
+	})
+
+	t.Run("cancel stops scrolling", func(t *testing.T) {
+		svr, _ := newPagingServer(t)
+		defer svr.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		zult, err := NewCMRSearchAPI().scroll(ctx, svr.URL, 0)
+		require.NoError(t, err)
+
+		first := <-zult.Ch
+		require.Equal(t, int64(1), first.Int())
+		cancel()
+
+		// the producer must close the channel without the receiver draining it
+		select {
+		case <-closed(zult.Ch):
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for scrolling to stop")
+		}
+		require.ErrorIs(t, zult.Err(), context.Canceled)
+	})
+}
+
+// closed returns a channel that is closed once ch is closed, discarding any values from ch
+func closed[T any](ch chan T) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range ch {
+		}
+	}()
+	return done
+}
+
+func TestCMRSearchAPIPageSize(t *testing.T) {
+	api := NewCMRSearchAPI()
+	require.Equal(t, 200, api.pageSizeFor(0))
+	require.Equal(t, 10, api.pageSizeFor(10))
+	require.Equal(t, 200, api.pageSizeFor(1000))
 }
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal/log"
+)
+
+// DefaultCookieMaxAge is how long cookies without an expiration, i.e., session cookies, are
+// persisted.
+var DefaultCookieMaxAge = 24 * time.Hour
+
+// storedCookie is a cookie as saved in the cookie file
+type storedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Path     string    `json:"path,omitempty"`
+	Domain   string    `json:"domain,omitempty"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+func (c *storedCookie) key() string {
+	u, _ := url.Parse(c.URL)
+	host := ""
+	if u != nil {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", host, c.Domain, c.Path, c.Name)
+}
+
+// PersistentJar is a http.CookieJar that saves cookies to a file so authentication cookies,
+// such as those from Earthdata Login, can be reused between runs. Cookies are persisted until
+// they expire, or for maxAge if they do not have an expiration. Expired cookies are purged
+// whenever the file is loaded or saved.
+//
+// It is safe for concurrent use.
+type PersistentJar struct {
+	*cookiejar.Jar
+	mu      sync.Mutex
+	path    string
+	maxAge  time.Duration
+	cookies map[string]storedCookie
+}
+
+// DefaultCookieJarPath is the location of the cookie file in the user cache dir.
+func DefaultCookieJarPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cmrfetch", "cookies.json"), nil
+}
+
+// NewPersistentJar creates a jar loading any existing, unexpired, cookies from path.
+func NewPersistentJar(path string, maxAge time.Duration) (*PersistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	pj := &PersistentJar{
+		Jar:     jar,
+		path:    path,
+		maxAge:  maxAge,
+		cookies: map[string]storedCookie{},
+	}
+	if err := pj.load(); err != nil {
+		return nil, fmt.Errorf("loading cookies: %w", err)
+	}
+	return pj, nil
+}
+
+func (j *PersistentJar) load() error {
+	fi, err := os.Stat(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if fi.Mode().Perm()&0o077 != 0 {
+		log.Printf("WARNING: cookie file %s is accessible by other users, fixing permissions", j.path)
+		if err := os.Chmod(j.path, 0o600); err != nil {
+			return err
+		}
+	}
+	dat, err := os.ReadFile(j.path)
+	if err != nil {
+		return err
+	}
+	stored := []storedCookie{}
+	if err := json.Unmarshal(dat, &stored); err != nil {
+		// A corrupt cookie file only costs us a login
+		log.Printf("WARNING: ignoring invalid cookie file %s: %s", j.path, err)
+		return nil
+	}
+	now := time.Now()
+	for _, c := range stored {
+		if !c.Expires.After(now) {
+			continue
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.cookies[c.key()] = c
+		j.Jar.SetCookies(u, []*http.Cookie{{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}})
+	}
+	log.Debug("loaded %d cookies from %s", len(j.cookies), j.path)
+	return nil
+}
+
+// SetCookies implements http.CookieJar, saving the cookies to the cookie file.
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		stored := storedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		switch {
+		case c.MaxAge < 0:
+			stored.Expires = now
+		case c.MaxAge > 0:
+			stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case c.Expires.IsZero():
+			stored.Expires = now.Add(j.maxAge)
+		}
+		if limit := now.Add(j.maxAge); stored.Expires.After(limit) {
+			stored.Expires = limit
+		}
+		if stored.Expires.After(now) {
+			j.cookies[stored.key()] = stored
+		} else {
+			delete(j.cookies, stored.key())
+		}
+	}
+	if err := j.save(); err != nil {
+		log.Printf("WARNING: failed to save cookies: %s", err)
+	}
+}
+
+// save writes unexpired cookies to the cookie file, readable only by the user. Must be
+// called with the lock held.
+func (j *PersistentJar) save() error {
+	now := time.Now()
+	stored := []storedCookie{}
+	for key, c := range j.cookies {
+		if !c.Expires.After(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		stored = append(stored, c)
+	}
+	dat, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(j.path, dat, 0o600)
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistentJar(t *testing.T) {
+	u, _ := url.Parse("https://urs.earthdata.nasa.gov/oauth")
+
+	t.Run("persists", func(t *testing.T) {
+		fpath := filepath.Join(t.TempDir(), "cmrfetch", "cookies.json")
+		jar, err := NewPersistentJar(fpath, time.Hour)
+		require.NoError(t, err)
+
+		jar.SetCookies(u, []*http.Cookie{
+			{Name: "session", Value: "S"},
+			{Name: "expires", Value: "E", Expires: time.Now().Add(time.Minute)},
+			{Name: "expired", Value: "X", Expires: time.Now().Add(-time.Minute)},
+		})
+
+		fi, err := os.Stat(fpath)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+		jar, err = NewPersistentJar(fpath, time.Hour)
+		require.NoError(t, err)
+		names := map[string]string{}
+		for _, c := range jar.Cookies(u) {
+			names[c.Name] = c.Value
+		}
+		require.Equal(t, map[string]string{"session": "S", "expires": "E"}, names)
+	})
+
+	t.Run("purges stale", func(t *testing.T) {
+		fpath := filepath.Join(t.TempDir(), "cookies.json")
+		jar, err := NewPersistentJar(fpath, time.Millisecond)
+		require.NoError(t, err)
+		jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "S"}})
+
+		time.Sleep(5 * time.Millisecond)
+
+		jar, err = NewPersistentJar(fpath, time.Hour)
+		require.NoError(t, err)
+		require.Empty(t, jar.Cookies(u))
+	})
+
+	t.Run("invalid file is ignored", func(t *testing.T) {
+		fpath := filepath.Join(t.TempDir(), "cookies.json")
+		require.NoError(t, os.WriteFile(fpath, []byte("xxx"), 0o600))
+
+		jar, err := NewPersistentJar(fpath, time.Hour)
+		require.NoError(t, err)
+		require.Empty(t, jar.Cookies(u))
+	})
+}
+
+func TestHTTPFetcherSharedJar(t *testing.T) {
+	defer mockNetrc(t)()
+
+	logins := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("auth"); err != nil {
+			logins++
+			http.SetCookie(w, &http.Cookie{Name: "auth", Value: "xxx", Path: "/"})
+		}
+		_, _ = w.Write([]byte("xxx"))
+	}))
+	defer svr.Close()
+
+	jar, err := NewPersistentJar(filepath.Join(t.TempDir(), "cookies.json"), time.Hour)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		fetcher, err := NewHTTPFetcherWithJar(true, "", jar)
+		require.NoError(t, err)
+		_, err = fetcher.Fetch(context.Background(), svr.URL+"/file", &bytes.Buffer{})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, logins, "fetchers sharing a jar should only login once")
+}
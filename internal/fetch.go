@@ -2,9 +2,6 @@ package internal
 
 import (
 	"context"
-	"crypto/md5"
-	"crypto/sha256"
-	"crypto/sha512"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -29,21 +26,6 @@ func SplitChecksum(s string) (string, string, error) {
 	return alg, val, nil
 }
 
-func newHash(alg string) (hash.Hash, error) {
-	var hash hash.Hash
-	switch strings.ToLower(alg) {
-	case "md5":
-		hash = md5.New()
-	case "sha256", "sha-256":
-		hash = sha256.New()
-	case "sha512", "sha-512":
-		hash = sha512.New()
-	default:
-		return nil, fmt.Errorf("expected one of MD5, SHA-256, SHA-512, got %q", alg)
-	}
-	return hash, nil
-}
-
 // writerHasher wraps a writer to compute a hash as bytes are written
 type writerHasher struct {
 	io.Writer
@@ -51,6 +33,8 @@ type writerHasher struct {
 	size int64
 	/// used to determine if written file is HTML
 	prefix []byte
+	// If set, called after every write with the number of bytes written and the total size
+	onWrite func(n int, size int64)
 }
 
 func (wh *writerHasher) Write(buf []byte) (int, error) {
@@ -62,6 +46,9 @@ func (wh *writerHasher) Write(buf []byte) (int, error) {
 		wh.hash.Write(buf[:n])
 	}
 	wh.size += int64(n)
+	if wh.onWrite != nil {
+		wh.onWrite(n, wh.size)
+	}
 	return n, err
 }
 
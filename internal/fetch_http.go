@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,7 +22,10 @@ type FailedDownload struct {
 	RequestID    string
 	ResponseBody string
 	Status       string
+	StatusCode   int
 	URL          string
+	// Server requested delay before retrying, if any
+	retryAfter time.Duration
 }
 
 func newFailedDownloadError(resp *http.Response) *FailedDownload {
@@ -34,14 +38,25 @@ func newFailedDownloadError(resp *http.Response) *FailedDownload {
 	if resp.Request != nil {
 		url = resp.Request.URL.String()
 	}
+	requestID := resp.Header.Get("request-id")
+	if requestID == "" {
+		// S3 uses its own header
+		requestID = resp.Header.Get("x-amz-request-id")
+	}
 	return &FailedDownload{
-		RequestID:    resp.Header.Get("request-id"),
+		RequestID:    requestID,
 		ResponseBody: body,
 		Status:       resp.Status,
+		StatusCode:   resp.StatusCode,
 		URL:          url,
+		retryAfter:   parseRetryAfter(resp.Header.Get("Retry-After")),
 	}
 }
 
+func (e *FailedDownload) HTTPStatusCode() int { return e.StatusCode }
+
+func (e *FailedDownload) RetryAfter() time.Duration { return e.retryAfter }
+
 func (e *FailedDownload) Error() string {
 	rid := e.RequestID
 	if rid == "" {
@@ -63,6 +78,19 @@ func ResolveEDLToken(token string) string {
 	return resolvedToken
 }
 
+// NetrcHasMachine returns true if the user's netrc file has an entry for host.
+func NetrcHasMachine(host string) (bool, error) {
+	fpath, err := defaultNetrcFinder()
+	if err != nil {
+		return false, err
+	}
+	nc, err := netrc.Parse(fpath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read netrc: %w", err)
+	}
+	return nc.Machine(host) != nil, nil
+}
+
 // Sets basic auth on redirect if the host is in the netrc file.
 func newRedirectWithNetrcCredentials() (func(*http.Request, []*http.Request) error, error) {
 	fpath, err := defaultNetrcFinder()
@@ -87,8 +115,8 @@ func newRedirectWithNetrcCredentials() (func(*http.Request, []*http.Request) err
 }
 
 // HTTPFetch is a Fetcher that supports basic file fetching. It supports netrc for authentication
-// redirects and uses an in-memory cookie jar to save authentication cookies provided by
-// authentication services such as NASA Einternal.
+// redirects and uses a cookie jar to save authentication cookies provided by authentication
+// services such as NASA Earthdata Login.
 type HTTPFetcher struct {
 	client   *http.Client
 	readSize int64
@@ -96,7 +124,17 @@ type HTTPFetcher struct {
 	bearerToken string
 }
 
+// NewHTTPFetcher creates a fetcher using an in-memory cookie jar. See NewHTTPFetcherWithJar.
 func NewHTTPFetcher(netrc bool, edlToken string) (*HTTPFetcher, error) {
+	return NewHTTPFetcherWithJar(netrc, edlToken, nil)
+}
+
+// NewHTTPFetcherWithJar creates a fetcher that uses either edlToken for bearer token auth, or
+// if not provided and netrc is true, netrc basic auth on redirect. When using netrc, jar is
+// used to store authentication cookies so login is not required for every request. Providing
+// the same jar to multiple fetchers lets them share authentication cookies. If jar is nil a new
+// in-memory jar is created.
+func NewHTTPFetcherWithJar(netrc bool, edlToken string, jar http.CookieJar) (*HTTPFetcher, error) {
 	client := &http.Client{
 		Timeout: 20 * time.Minute,
 	}
@@ -104,11 +142,15 @@ func NewHTTPFetcher(netrc bool, edlToken string) (*HTTPFetcher, error) {
 	// Token has priority over netrc if set
 	if edlToken == "" && netrc {
 		// Netrc needs a cookiejar so we don't have to do redirect everytime
-		jar, err := cookiejar.New(nil)
-		if err != nil {
-			return nil, fmt.Errorf("creating cookiejar: %w", err)
+		if jar == nil {
+			var err error
+			jar, err = cookiejar.New(nil)
+			if err != nil {
+				return nil, fmt.Errorf("creating cookiejar: %w", err)
+			}
 		}
 		client.Jar = jar
+		var err error
 		client.CheckRedirect, err = newRedirectWithNetrcCredentials()
 		if err != nil {
 			return nil, fmt.Errorf("configuring netrc token redirect: %w", err)
@@ -135,33 +177,91 @@ func (f *HTTPFetcher) newRequest(ctx context.Context, url string) (*http.Request
 	return req, nil
 }
 
+// resumeValidator returns the value to use for If-Range from the response, preferring a strong
+// ETag over Last-Modified. Weak ETags cannot be used for range requests.
+func resumeValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
 // Fetch url to destdir using url's basename as the filename and update hash with the file
 // bytes as they are read.
+//
+// If w is a ResumableWriter with partial content a range request is made for the remaining
+// bytes. If the server does not honor the range the partial content is truncated and the
+// full content is written.
 func (f *HTTPFetcher) Fetch(ctx context.Context, url string, w io.Writer) (int64, error) {
-	req, err := f.newRequest(ctx, url)
+	return fetchHTTP(f.client, func() (*http.Request, error) {
+		return f.newRequest(ctx, url)
+	}, w, f.readSize)
+}
+
+// fetchHTTP performs the request created by newRequest writing the response body to w. See
+// HTTPFetcher.Fetch for details on resuming.
+func fetchHTTP(client *http.Client, newRequest func() (*http.Request, error), w io.Writer, readSize int64) (int64, error) {
+	req, err := newRequest()
 	if err != nil {
 		return 0, err
 	}
 
-	resp, err := f.client.Do(req)
-	if err != nil {
-		return 0, err
+	rw, resumable := w.(ResumableWriter)
+	if resumable && rw.Offset() > 0 && rw.Validator() != "" {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", rw.Offset()))
+		req.Header.Set("If-Range", rw.Validator())
 	}
-	if resp.StatusCode != http.StatusOK {
-		err = newFailedDownloadError(resp)
-		resp.Body.Close()
+
+	resp, err := client.Do(req)
+	if err != nil {
 		return 0, err
 	}
 	defer resp.Body.Close()
 
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		if resumable && rw.Offset() > 0 {
+			// Server sent the full content, either b/c it does not support ranges or the
+			// content changed since the partial was written.
+			if err := rw.Truncate(); err != nil {
+				return 0, err
+			}
+		}
+	case resp.StatusCode == http.StatusPartialContent && resumable:
+		if start := contentRangeStart(resp); start != rw.Offset() {
+			if rw.Offset() == 0 {
+				return 0, fmt.Errorf("expected content range starting at 0, got %d", start)
+			}
+			// The same range would fail again, so discard the partial content and start over
+			if err := rw.Truncate(); err != nil {
+				return 0, err
+			}
+			return fetchHTTP(client, newRequest, w, readSize)
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && resumable:
+		// Partial content is no longer valid for the remote file, start over
+		if err := rw.Truncate(); err != nil {
+			return 0, err
+		}
+		return fetchHTTP(client, newRequest, w, readSize)
+	default:
+		return 0, newFailedDownloadError(resp)
+	}
+
+	if resumable {
+		if err := rw.SetValidator(resumeValidator(resp)); err != nil {
+			return 0, fmt.Errorf("saving resume validator: %w", err)
+		}
+	}
+
 	var size int64
-	buf := make([]byte, f.readSize)
+	buf := make([]byte, readSize)
 	r := bufio.NewReader(resp.Body)
 	for {
 		n, rErr := r.Read(buf)
 		_, wErr := w.Write(buf[:n])
 		if wErr != nil {
-			return size, fmt.Errorf("writing to file: %w", err)
+			return size, fmt.Errorf("writing to file: %w", wErr)
 		}
 
 		size += int64(n)
@@ -175,3 +275,17 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, url string, w io.Writer) (int64
 	}
 	return size, nil
 }
+
+// contentRangeStart returns the first byte position from a Content-Range header, or -1 if
+// it cannot be parsed.
+func contentRangeStart(resp *http.Response) int64 {
+	var start, end, total int64
+	val := resp.Header.Get("Content-Range")
+	if _, err := fmt.Sscanf(val, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		// total may be unknown, i.e., */
+		if _, err := fmt.Sscanf(val, "bytes %d-%d/*", &start, &end); err != nil {
+			return -1
+		}
+	}
+	return start
+}
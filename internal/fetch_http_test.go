@@ -3,12 +3,16 @@ package internal
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -88,6 +92,18 @@ func Test_newRedirectWithNetrcCredentials(t *testing.T) {
 	require.Equal(t, "PASSWORD", passwd)
 }
 
+func TestNetrcHasMachine(t *testing.T) {
+	defer mockNetrc(t)()
+
+	ok, err := NetrcHasMachine("testhost.com")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = NetrcHasMachine("otherhost.com")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
 func TestHTTPFetcher(t *testing.T) {
 	defer mockNetrc(t)()
 
@@ -125,6 +141,81 @@ func TestHTTPFetcher(t *testing.T) {
 	})
 }
 
+func TestHTTPFetcherResume(t *testing.T) {
+	body := []byte("0123456789")
+	sum := md5.Sum(body)
+	expected := hex.EncodeToString(sum[:])
+
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("ETag", `"etag"`)
+		http.ServeContent(w, req, "file.txt", time.Time{}, bytes.NewReader(body))
+	}))
+	defer svr.Close()
+	url := fmt.Sprintf("http://%s/file.txt", svr.Listener.Addr())
+
+	fetcher, err := NewHTTPFetcher(false, "")
+	require.NoError(t, err)
+
+	t.Run("range honored", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file.txt")
+		writePartial(t, dest, url, `"etag"`, body[:4])
+
+		pw, err := openPartial(dest, url, md5.New())
+		require.NoError(t, err)
+		defer pw.Close()
+
+		size, err := fetcher.Fetch(context.Background(), url, pw)
+		require.NoError(t, err)
+		require.Equal(t, int64(6), size, "should only fetch remaining bytes")
+		require.Equal(t, expected, pw.Checksum())
+	})
+
+	t.Run("validator mismatch fetches everything", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file.txt")
+		writePartial(t, dest, url, `"stale"`, []byte("xxxx"))
+
+		pw, err := openPartial(dest, url, md5.New())
+		require.NoError(t, err)
+		defer pw.Close()
+
+		size, err := fetcher.Fetch(context.Background(), url, pw)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(body)), size)
+		require.Equal(t, expected, pw.Checksum())
+		require.Equal(t, `"etag"`, pw.Validator())
+	})
+
+	t.Run("content range mismatch fetches everything", func(t *testing.T) {
+		ranges := []string{}
+		svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ranges = append(ranges, req.Header.Get("Range"))
+			if req.Header.Get("Range") != "" {
+				// wrong start for the requested range
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 2-9/%d", len(body)))
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = w.Write(body[2:])
+				return
+			}
+			w.Header().Set("ETag", `"etag"`)
+			_, _ = w.Write(body)
+		}))
+		defer svr.Close()
+
+		dest := filepath.Join(t.TempDir(), "file.txt")
+		writePartial(t, dest, svr.URL, `"etag"`, body[:4])
+
+		pw, err := openPartial(dest, svr.URL, md5.New())
+		require.NoError(t, err)
+		defer pw.Close()
+
+		size, err := fetcher.Fetch(context.Background(), svr.URL, pw)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(body)), size)
+		require.Equal(t, expected, pw.Checksum())
+		require.Equal(t, []string{"bytes=4-", ""}, ranges)
+	})
+}
+
 func TestHTTPFetcherRequest(t *testing.T) {
 	fetcher, err := NewHTTPFetcher(false, "XXX")
 	require.NoError(t, err)
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ResumableWriter is implemented by writers that may already contain content from a previous,
+// interrupted download. Fetchers that support resuming can use it to only request the bytes
+// that are missing.
+type ResumableWriter interface {
+	io.Writer
+	// Offset is the number of bytes already present from a previous attempt.
+	Offset() int64
+	// Validator is the ETag or Last-Modified value recorded for the partial content, if any.
+	Validator() string
+	// SetValidator records the ETag or Last-Modified value for the content being written so a
+	// later attempt can resume.
+	SetValidator(string) error
+	// Truncate discards all partial content so the download can start from the beginning.
+	Truncate() error
+}
+
+// partialMeta is the sidecar data saved alongside a partial download
+type partialMeta struct {
+	URL       string `json:"url"`
+	Validator string `json:"validator"`
+}
+
+// partialWriter writes a download to a stable partial file in the destination directory such
+// that an interrupted download may be resumed.
+type partialWriter struct {
+	*writerHasher
+	file      *os.File
+	metaPath  string
+	url       string
+	validator string
+	offset    int64
+}
+
+var _ ResumableWriter = (*partialWriter)(nil)
+
+func partialPaths(dest string) (string, string) {
+	dir, name := filepath.Split(dest)
+	fpath := filepath.Join(dir, fmt.Sprintf(".%s.partial", name))
+	return fpath, fpath + ".json"
+}
+
+// openPartial opens, or creates, the partial file for dest. If there is existing partial
+// content that was downloaded from url it is read through hash, so the final checksum covers
+// the entire file, and the writer is positioned to append. Otherwise, any existing content is
+// discarded.
+func openPartial(dest, url string, hash hash.Hash) (*partialWriter, error) {
+	fpath, metaPath := partialPaths(dest)
+	file, err := os.OpenFile(fpath, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	pw := &partialWriter{
+		writerHasher: &writerHasher{Writer: file, hash: hash},
+		file:         file,
+		metaPath:     metaPath,
+		url:          url,
+	}
+
+	meta, err := readPartialMeta(metaPath)
+	if err != nil || meta.URL != url || meta.Validator == "" {
+		// Without a validator there's no way to know if the partial content is still valid
+		return pw, pw.Truncate()
+	}
+	pw.validator = meta.Validator
+
+	// Prime the hasher with the existing content; writes through the hasher are discarded
+	// because the content is already in the file.
+	primer := &writerHasher{Writer: io.Discard, hash: hash}
+	if _, err := io.Copy(primer, file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reading partial content: %w", err)
+	}
+	pw.prefix = primer.prefix
+	pw.size = primer.size
+	pw.offset = primer.size
+
+	return pw, nil
+}
+
+func readPartialMeta(fpath string) (partialMeta, error) {
+	meta := partialMeta{}
+	dat, err := os.ReadFile(fpath)
+	if err != nil {
+		return meta, err
+	}
+	return meta, json.Unmarshal(dat, &meta)
+}
+
+func (pw *partialWriter) Offset() int64 { return pw.offset }
+
+func (pw *partialWriter) Validator() string { return pw.validator }
+
+func (pw *partialWriter) SetValidator(val string) error {
+	pw.validator = val
+	dat, err := json.Marshal(partialMeta{URL: pw.url, Validator: val})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(pw.metaPath, dat, 0o644)
+}
+
+func (pw *partialWriter) Truncate() error {
+	if err := pw.file.Truncate(0); err != nil {
+		return fmt.Errorf("truncating partial: %w", err)
+	}
+	if _, err := pw.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking partial: %w", err)
+	}
+	if pw.hash != nil {
+		pw.hash.Reset()
+	}
+	pw.prefix = nil
+	pw.size = 0
+	pw.offset = 0
+	pw.validator = ""
+	if err := os.Remove(pw.metaPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (pw *partialWriter) Name() string { return pw.file.Name() }
+
+func (pw *partialWriter) Close() error { return pw.file.Close() }
+
+// Commit renames the completed partial file to dest and removes the sidecar metadata.
+func (pw *partialWriter) Commit(dest string) error {
+	if err := os.Rename(pw.file.Name(), dest); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %w", pw.file.Name(), dest, err)
+	}
+	if err := os.Remove(pw.metaPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Discard removes the partial file and its metadata. It should be used when the partial
+// content is known to be bad and should not be resumed.
+func (pw *partialWriter) Discard() {
+	pw.file.Close()
+	os.Remove(pw.file.Name())
+	os.Remove(pw.metaPath)
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePartial(t *testing.T, dest, url, validator string, content []byte) {
+	t.Helper()
+
+	fpath, metaPath := partialPaths(dest)
+	require.NoError(t, os.WriteFile(fpath, content, 0o644))
+	if validator != "" {
+		pw := &partialWriter{metaPath: metaPath, url: url}
+		require.NoError(t, pw.SetValidator(validator))
+	}
+}
+
+func Test_openPartial(t *testing.T) {
+	body := []byte("0123456789")
+	sum := md5.Sum(body)
+	expected := hex.EncodeToString(sum[:])
+
+	t.Run("resumes with validator", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file.txt")
+		writePartial(t, dest, "http://host/file.txt", `"etag"`, body[:4])
+
+		pw, err := openPartial(dest, "http://host/file.txt", md5.New())
+		require.NoError(t, err)
+		defer pw.Close()
+
+		require.Equal(t, int64(4), pw.Offset())
+		require.Equal(t, `"etag"`, pw.Validator())
+
+		_, err = pw.Write(body[4:])
+		require.NoError(t, err)
+		require.Equal(t, expected, pw.Checksum(), "checksum should cover entire file")
+		require.Equal(t, int64(len(body)), pw.size)
+
+		require.NoError(t, pw.Commit(dest))
+		dat, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		require.Equal(t, body, dat)
+
+		_, metaPath := partialPaths(dest)
+		require.NoFileExists(t, metaPath)
+	})
+
+	t.Run("no validator starts over", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file.txt")
+		writePartial(t, dest, "http://host/file.txt", "", body[:4])
+
+		pw, err := openPartial(dest, "http://host/file.txt", md5.New())
+		require.NoError(t, err)
+		defer pw.Close()
+
+		require.Equal(t, int64(0), pw.Offset())
+	})
+
+	t.Run("different url starts over", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file.txt")
+		writePartial(t, dest, "http://host/other.txt", `"etag"`, body[:4])
+
+		pw, err := openPartial(dest, "http://host/file.txt", md5.New())
+		require.NoError(t, err)
+		defer pw.Close()
+
+		require.Equal(t, int64(0), pw.Offset())
+		require.Equal(t, "", pw.Validator())
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "file.txt")
+		writePartial(t, dest, "http://host/file.txt", `"etag"`, body[:4])
+
+		pw, err := openPartial(dest, "http://host/file.txt", md5.New())
+		require.NoError(t, err)
+		defer pw.Close()
+
+		require.NoError(t, pw.Truncate())
+		_, err = pw.Write(body)
+		require.NoError(t, err)
+		require.Equal(t, expected, pw.Checksum())
+	})
+}
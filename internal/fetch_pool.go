@@ -3,12 +3,15 @@ package internal
 import (
 	"context"
 	"fmt"
+	"hash"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/bmflynn/cmrfetch/internal/log"
 )
 
 type FetchError struct {
@@ -20,15 +23,40 @@ func (e *FetchError) Error() string {
 	return fmt.Sprintf("fetching: %s", e.Err)
 }
 
+func (e *FetchError) Unwrap() error { return e.Err }
+
 type FetcherFactory func() (Fetcher, error)
 
+// ProgressTracker is notified of download progress by the workers in a fetch pool. Workers are
+// identified by their index in the pool, starting at 0. Implementations must be safe for
+// concurrent use.
+type ProgressTracker interface {
+	// Started is called when worker starts a download attempt for req
+	Started(worker int, req DownloadRequest)
+	// Wrote is called as worker writes n bytes, where size is the total size of the file
+	// written so far, including content from a resumed download.
+	Wrote(worker int, n int, size int64)
+	// Finished is called when worker is done with a request
+	Finished(worker int, zult DownloadResult)
+}
+
 type FetchPoolFunc = func(reqs chan DownloadRequest, concurrency int) (chan DownloadResult, error)
 
 func FetchConcurrent(reqs chan DownloadRequest, fetcherFactory FetcherFactory, concurrency int) (chan DownloadResult, error) {
-	return FetchConcurrentWithContext(context.Background(), reqs, fetcherFactory, concurrency)
+	return FetchConcurrentWithContext(context.Background(), reqs, fetcherFactory, concurrency, DefaultRetryPolicy, nil)
 }
 
-func FetchConcurrentWithContext(ctx context.Context, reqs chan DownloadRequest, fetcherFactory FetcherFactory, concurrency int) (chan DownloadResult, error) {
+// FetchConcurrentWithContext downloads reqs using concurrency fetchers created by fetcherFactory.
+// Failed downloads are retried according to policy if the failure is retryable. If progress is
+// not nil it is notified of the progress of every download.
+func FetchConcurrentWithContext(
+	ctx context.Context,
+	reqs chan DownloadRequest,
+	fetcherFactory FetcherFactory,
+	concurrency int,
+	policy RetryPolicy,
+	progress ProgressTracker,
+) (chan DownloadResult, error) {
 	results := make(chan DownloadResult)
 
 	wg := &sync.WaitGroup{}
@@ -38,7 +66,7 @@ func FetchConcurrentWithContext(ctx context.Context, reqs chan DownloadRequest,
 		if err != nil {
 			return nil, fmt.Errorf("failed to init fetcher: %w", err)
 		}
-		go downloader(ctx, wg, reqs, results, fetcher)
+		go downloader(ctx, wg, i, reqs, results, fetcher, policy, progress)
 	}
 
 	// Close results once all the downloaders have exited
@@ -55,6 +83,8 @@ type DownloadRequest struct {
 	ChecksumAlg string
 	Checksum    string
 	Dest        string
+	// Expected size in bytes, if known
+	Size int64
 }
 
 type DownloadResult struct {
@@ -64,20 +94,27 @@ type DownloadResult struct {
 	ChecksumVerificationSkipped string // if we skipped checksum verification, this is why
 	Duration                    time.Duration
 	Size                        int64
+	Attempts                    int
 	Err                         error
 }
 
 // downloader downloads all requests using fetcher sending results to results. The provided context
 // is used to cancel in-flight requests to the fetcher.
 //
-// All errors are of type *FetchError and will terminate the downloader.
+// Downloads are written to a partial file alongside the destination which is kept if the fetch
+// fails, allowing fetchers that support ResumableWriter to resume the download on a later attempt.
+//
+// Failed downloads are retried according to policy. All errors are of type *FetchError.
 func downloader(
 	// Cancels in-flight download requests when canceled
 	ctx context.Context,
 	wg *sync.WaitGroup,
+	worker int,
 	requests chan DownloadRequest,
 	results chan DownloadResult,
 	fetch Fetcher,
+	policy RetryPolicy,
+	progress ProgressTracker,
 ) {
 	defer wg.Done()
 
@@ -87,54 +124,85 @@ func downloader(
 			Path: req.Dest,
 		}
 
-		// in a func so we can defer the close and make collecting the error easier
-		err := func() error {
-			start := time.Now()
-
-			destdir, fname := filepath.Split(zult.Path)
-			dest, err := os.CreateTemp(destdir, fmt.Sprintf(".%s.*", fname))
-			if err != nil {
-				return fmt.Errorf("creating dest: %w", err)
-			}
-			defer os.Remove(dest.Name())
-
-			w := &writerHasher{Writer: dest}
-			if req.ChecksumAlg != "" {
-				w.hash, err = newHash(req.ChecksumAlg)
-				if err != nil {
-					zult.ChecksumVerificationSkipped = err.Error()
-				}
-			}
-			_, err = fetch(ctx, zult.URL, w)
-			if err != nil {
-				return err
-			}
-			dest.Close() // Close before checksumming
-
-			if strings.HasPrefix(path.Ext(req.URL), ".htm") && w.ProbableHtml() {
-				return fmt.Errorf("probable HTML download content, possibly indicating a bad auth redirect")
+		var onWrite func(int, int64)
+		if progress != nil {
+			onWrite = func(n int, size int64) { progress.Wrote(worker, n, size) }
+		}
+		attempts, err := policy.Do(ctx, func() error {
+			if progress != nil {
+				progress.Started(worker, req)
 			}
+			return download(ctx, fetch, req, &zult, onWrite)
+		}, func(attempt int, delay time.Duration, err error) {
+			log.Printf("retrying %s in %s, attempt %d of %d failed: %s",
+				req.URL, delay.Round(time.Millisecond), attempt, policy.attempts(), err)
+		})
+		zult.Attempts = attempts
+		if err != nil {
+			zult.Err = &FetchError{Request: req, Err: err}
+		}
+		if progress != nil {
+			progress.Finished(worker, zult)
+		}
 
-			zult.Checksum = w.Checksum()
-			if zult.Checksum != "" && zult.Checksum != req.Checksum {
-				return fmt.Errorf("got checksum %s, expected %s", zult.Checksum, req.Checksum)
-			}
+		results <- zult // success!
+	}
+}
 
-			zult.Size = w.size
-			zult.Duration = time.Since(start)
+// download performs a single download attempt for req, updating zult. onWrite, if not nil, is
+// called as bytes are written.
+func download(ctx context.Context, fetch Fetcher, req DownloadRequest, zult *DownloadResult, onWrite func(int, int64)) error {
+	start := time.Now()
 
-			if err := os.Rename(dest.Name(), zult.Path); err != nil {
-				return fmt.Errorf("failed to rename %s to %s: %w", dest.Name(), zult.Path, err)
-			}
-			if err := os.Chmod(zult.Path, 0o644); err != nil {
-				return fmt.Errorf("failed to update permissions on %s: %w", zult.Path, err)
-			}
-			return nil
-		}()
+	var hash hash.Hash
+	if req.ChecksumAlg != "" {
+		var err error
+		hash, err = newHash(req.ChecksumAlg)
 		if err != nil {
-			zult.Err = &FetchError{Request: req, Err: err}
+			zult.ChecksumVerificationSkipped = err.Error()
 		}
+	}
 
-		results <- zult // success!
+	if err := os.MkdirAll(filepath.Dir(zult.Path), 0o755); err != nil {
+		return fmt.Errorf("creating dest dir: %w", err)
+	}
+
+	// Partial content is kept on failure so a later attempt may resume
+	dest, err := openPartial(zult.Path, req.URL, hash)
+	if err != nil {
+		return fmt.Errorf("creating dest: %w", err)
+	}
+	defer dest.Close()
+	dest.onWrite = onWrite
+	if dest.Offset() > 0 {
+		log.Debug("resuming %s at offset %d", zult.Path, dest.Offset())
+	}
+
+	_, err = fetch(ctx, zult.URL, dest)
+	if err != nil {
+		return err
+	}
+	dest.Close() // Close before checksumming
+
+	if strings.HasPrefix(path.Ext(req.URL), ".htm") && dest.ProbableHtml() {
+		dest.Discard()
+		return fmt.Errorf("probable HTML download content, possibly indicating a bad auth redirect")
+	}
+
+	zult.Checksum = dest.Checksum()
+	if zult.Checksum != "" && !ChecksumsEqual(req.ChecksumAlg, zult.Checksum, req.Checksum) {
+		dest.Discard()
+		return fmt.Errorf("got checksum %s, expected %s", zult.Checksum, req.Checksum)
+	}
+
+	zult.Size = dest.size
+	zult.Duration = time.Since(start)
+
+	if err := dest.Commit(zult.Path); err != nil {
+		return err
+	}
+	if err := os.Chmod(zult.Path, 0o644); err != nil {
+		return fmt.Errorf("failed to update permissions on %s: %w", zult.Path, err)
 	}
+	return nil
 }
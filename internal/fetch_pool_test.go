@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -46,3 +47,66 @@ func TestFetchConcurrent(t *testing.T) {
 
 	require.Len(t, results, 1)
 }
+
+func TestFetchConcurrentDecimalChecksum(t *testing.T) {
+	dir := t.TempDir()
+
+	fetcher := func(ctx context.Context, url string, w io.Writer) (int64, error) {
+		n, err := w.Write([]byte("abcde"))
+		return int64(n), err
+	}
+
+	requests := make(chan DownloadRequest, 1)
+	requests <- DownloadRequest{
+		URL:         "doesn't matter",
+		ChecksumAlg: "POSIX",
+		// as published by cksum
+		Checksum: "996742021",
+		Dest:     filepath.Join(dir, "testoutput.txt"),
+	}
+	close(requests)
+
+	policy := RetryPolicy{MaxAttempts: 1}
+	resultsCh, err := FetchConcurrentWithContext(
+		context.Background(), requests, func() (Fetcher, error) { return fetcher, nil }, 1, policy, nil)
+	require.NoError(t, err)
+
+	zult := <-resultsCh
+	require.NoError(t, zult.Err)
+	require.FileExists(t, zult.Path)
+}
+
+func TestFetchConcurrentRetry(t *testing.T) {
+	dir := t.TempDir()
+
+	body := []byte("xxx")
+	calls := 0
+	fetcher := func(ctx context.Context, url string, w io.Writer) (int64, error) {
+		calls++
+		if calls == 1 {
+			return 0, &FailedDownload{StatusCode: 503}
+		}
+		n, err := w.Write(body)
+		return int64(n), err
+	}
+
+	requests := make(chan DownloadRequest, 1)
+	requests <- DownloadRequest{
+		URL:  "doesn't matter",
+		Dest: filepath.Join(dir, "testoutput.txt"),
+	}
+	close(requests)
+
+	policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	resultsCh, err := FetchConcurrentWithContext(
+		context.Background(), requests, func() (Fetcher, error) { return fetcher, nil }, 1, policy, nil)
+	require.NoError(t, err)
+
+	zult := <-resultsCh
+	require.NoError(t, zult.Err)
+	require.Equal(t, 2, zult.Attempts)
+
+	dat, err := os.ReadFile(zult.Path)
+	require.NoError(t, err)
+	require.Equal(t, body, dat)
+}
@@ -0,0 +1,344 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal/log"
+)
+
+// Earthdata Cloud buckets are all in us-west-2
+const defaultS3Region = "us-west-2"
+
+// Credentials are refreshed this long before they expire so they don't expire mid-download
+var s3CredentialsRefreshWindow = 5 * time.Minute
+
+// S3Credentials are temporary credentials as provided by a DAAC s3credentials endpoint.
+type S3Credentials struct {
+	AccessKeyID     string    `json:"accessKeyId"`
+	SecretAccessKey string    `json:"secretAccessKey"`
+	SessionToken    string    `json:"sessionToken"`
+	Expiration      time.Time `json:"-"`
+}
+
+func (c *S3Credentials) expired() bool {
+	return time.Until(c.Expiration) < s3CredentialsRefreshWindow
+}
+
+var s3ExpirationLayouts = []string{
+	"2006-01-02 15:04:05-07:00",
+	time.RFC3339,
+}
+
+func decodeS3Credentials(r io.Reader) (*S3Credentials, error) {
+	dat := struct {
+		S3Credentials
+		Expiration string `json:"expiration"`
+	}{}
+	if err := json.NewDecoder(r).Decode(&dat); err != nil {
+		return nil, fmt.Errorf("decoding s3 credentials: %w", err)
+	}
+	creds := dat.S3Credentials
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 credentials response missing access key")
+	}
+	for _, layout := range s3ExpirationLayouts {
+		if t, err := time.Parse(layout, dat.Expiration); err == nil {
+			creds.Expiration = t
+			return &creds, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid s3 credentials expiration %q", dat.Expiration)
+}
+
+// S3CredentialStore fetches and caches temporary S3 credentials from DAAC s3credentials
+// endpoints. Credentials are refreshed shortly before they expire. It is safe for concurrent
+// use and is intended to be shared by all S3Fetchers in a pool.
+type S3CredentialStore struct {
+	mu sync.Mutex
+	// fetch is used to fetch credentials and must handle Earthdata Login authentication
+	fetch Fetcher
+	// used for buckets that do not have an endpoint set
+	defaultEndpoint string
+	// bucket name to endpoint
+	endpoints map[string]string
+	// endpoint to credentials
+	creds map[string]*S3Credentials
+}
+
+// NewS3CredentialStore creates a store that uses fetch, which must handle Earthdata Login
+// authentication, e.g., HTTPFetcher.Fetch, to get credentials. If defaultEndpoint is provided
+// it will be used for any bucket without an endpoint.
+func NewS3CredentialStore(fetch Fetcher, defaultEndpoint string) *S3CredentialStore {
+	return &S3CredentialStore{
+		fetch:           fetch,
+		defaultEndpoint: defaultEndpoint,
+		endpoints:       map[string]string{},
+		creds:           map[string]*S3Credentials{},
+	}
+}
+
+// SetEndpoint sets the s3credentials endpoint to use for bucket.
+func (s *S3CredentialStore) SetEndpoint(bucket, endpoint string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.endpoints[bucket] = endpoint
+}
+
+// Get returns valid credentials for bucket, fetching new credentials if necessary.
+func (s *S3CredentialStore) Get(ctx context.Context, bucket string) (*S3Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint, ok := s.endpoints[bucket]
+	if !ok {
+		endpoint = s.defaultEndpoint
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("no s3credentials endpoint for bucket %s", bucket)
+	}
+	if creds, ok := s.creds[endpoint]; ok && !creds.expired() {
+		return creds, nil
+	}
+
+	log.Debug("fetching s3 credentials from %s", endpoint)
+	buf := &bytes.Buffer{}
+	if _, err := s.fetch(ctx, endpoint, buf); err != nil {
+		return nil, fmt.Errorf("fetching s3 credentials: %w", err)
+	}
+	creds, err := decodeS3Credentials(buf)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("s3 credentials from %s expire %s", endpoint, creds.Expiration)
+	s.creds[endpoint] = creds
+	return creds, nil
+}
+
+// Invalidate discards any cached credentials for bucket.
+func (s *S3CredentialStore) Invalidate(bucket string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint, ok := s.endpoints[bucket]
+	if !ok {
+		endpoint = s.defaultEndpoint
+	}
+	delete(s.creds, endpoint)
+}
+
+// ParseS3URL splits a s3://<bucket>/<key> url into bucket and key.
+func ParseS3URL(s3url string) (string, string, error) {
+	u, err := url.Parse(s3url)
+	if err != nil {
+		return "", "", err
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "s3" || u.Host == "" || key == "" {
+		return "", "", fmt.Errorf("expected s3://<bucket>/<key>, got %s", s3url)
+	}
+	return u.Host, key, nil
+}
+
+// S3Fetcher is a Fetcher for s3://<bucket>/<key> urls, such as granule direct access urls,
+// using temporary credentials from a S3CredentialStore.
+type S3Fetcher struct {
+	client   *http.Client
+	creds    *S3CredentialStore
+	region   string
+	endpoint string
+	readSize int64
+}
+
+// NewS3Fetcher creates a fetcher using credentials from creds. If endpoint is not empty it is
+// used as the url of a S3 compatible service using path style requests, otherwise AWS S3 in
+// region is used.
+func NewS3Fetcher(creds *S3CredentialStore, region, endpoint string) *S3Fetcher {
+	if region == "" {
+		region = defaultS3Region
+	}
+	return &S3Fetcher{
+		client:   &http.Client{Timeout: 20 * time.Minute},
+		creds:    creds,
+		region:   region,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		readSize: 2 << 19,
+	}
+}
+
+func (f *S3Fetcher) objectURL(bucket, key string) (*url.URL, error) {
+	var u *url.URL
+	var err error
+	if f.endpoint != "" {
+		u, err = url.Parse(f.endpoint + "/" + bucket)
+	} else {
+		u, err = url.Parse(fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, f.region))
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/" + key
+	u.RawPath = awsURIEncode(u.Path, false)
+	return u, nil
+}
+
+func (f *S3Fetcher) newRequest(ctx context.Context, s3url string) (*http.Request, error) {
+	bucket, key, err := ParseS3URL(s3url)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := f.creds.Get(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	u, err := f.objectURL(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	signV4(req, creds, f.region, "s3", time.Now())
+	return req, nil
+}
+
+// Fetch the s3:// url, writing the object to w. See HTTPFetcher.Fetch for details on resuming.
+func (f *S3Fetcher) Fetch(ctx context.Context, s3url string, w io.Writer) (int64, error) {
+	newRequest := func() (*http.Request, error) { return f.newRequest(ctx, s3url) }
+	size, err := fetchHTTP(f.client, newRequest, w, f.readSize)
+
+	// Credentials may have been revoked or expired early, try once more with new credentials
+	var dlErr *FailedDownload
+	if errors.As(err, &dlErr) && dlErr.StatusCode == http.StatusForbidden &&
+		strings.Contains(dlErr.ResponseBody, "ExpiredToken") {
+		bucket, _, _ := ParseS3URL(s3url)
+		log.Debug("s3 credentials expired for %s, refreshing", bucket)
+		f.creds.Invalidate(bucket)
+		return fetchHTTP(f.client, newRequest, w, f.readSize)
+	}
+	return size, err
+}
+
+// Payload hash for requests that don't sign the payload
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// signV4 signs req using AWS Signature Version 4. Only the host and x-amz-* headers are
+// signed, so headers such as Range may be set after signing, e.g., when resuming a download.
+//
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func signV4(req *http.Request, creds *S3Credentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := req.Header.Get("x-amz-content-sha256")
+	if payloadHash == "" {
+		payloadHash = unsignedPayload
+		req.Header.Set("x-amz-content-sha256", payloadHash)
+	}
+	req.Header.Set("x-amz-date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, vals := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(vals, ","))
+		}
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(crHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := []string{}
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, k := range keys {
+		vals := query[k]
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode encodes s per the SigV4 rules, where everything other than unreserved
+// characters is percent encoded. Slashes are only encoded if encodeSlash is true.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -8,54 +8,56 @@ import (
 	"github.com/spf13/pflag"
 )
 
-var timeRangeLayouts = []string{
-	time.RFC3339,
-	"2006-01-02",
-}
-
+// TimeRangeValue is a flag value for a time range of <start>,[<end>], where start and end are
+// time expressions; see ParseTimeExpr.
 type TimeRangeValue struct {
 	Start *time.Time
 	End   *time.Time
 }
 
+// NewTimeRangeValue returns a default range of 24 hours ago to now.
 func NewTimeRangeValue() TimeRangeValue {
-	tr := TimeRangeValue{}
-	tr.Set(tr.String())
-	return tr
+	start := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+	return TimeRangeValue{Start: &start}
 }
 
-// String returns a default range of 24 hours ago to now.
+// String returns the range as <start>,[<end>], or an empty string if the range is not set.
 func (v *TimeRangeValue) String() string {
-	now := time.Now().Add(-24 * time.Hour).UTC()
-	return now.Format(time.RFC3339) + ","
-}
-
-func (v *TimeRangeValue) parse(s string) *time.Time {
-	for _, layout := range timeRangeLayouts {
-		t, err := time.Parse(layout, s)
-		if err == nil {
-			return &t
-		}
+	if v.Start == nil {
+		return ""
 	}
-	return nil
+	s := v.Start.Format(time.RFC3339) + ","
+	if v.End != nil {
+		s += v.End.Format(time.RFC3339)
+	}
+	return s
 }
 
 func (tr *TimeRangeValue) Set(val string) error {
 	parts := strings.SplitN(val, ",", 2)
 	if len(parts) != 2 {
-		return fmt.Errorf("expected <start>,[<end>], with format <yyyy-mm-dd>[T<hh:mm:ss>Z]")
+		return fmt.Errorf("expected <start>,[<end>], where times are %s", TimeExprHelp)
+	}
+	if strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("a start time is required, only the end of a range may be open, e.g., 2023-01-01,")
 	}
 
+	now := timeNow()
 	x := &TimeRangeValue{}
-	x.Start = x.parse(parts[0])
-	if x.Start == nil {
-		return fmt.Errorf("invalid start time format; expected <yyyy-mm-dd>[T<hh:mm:ss>Z]")
-	}
-	if parts[1] != "" {
-		x.End = x.parse(parts[1])
-		if x.End == nil {
-			return fmt.Errorf("invalid end time format; expected <yyyy-mm-dd>[T<hh:mm:ss>Z]")
+	start, err := ParseTimeExpr(parts[0], now)
+	if err != nil {
+		return fmt.Errorf("invalid start: %w", err)
+	}
+	x.Start = &start
+	if strings.TrimSpace(parts[1]) != "" {
+		end, err := ParseTimeExpr(parts[1], now)
+		if err != nil {
+			return fmt.Errorf("invalid end: %w", err)
 		}
+		if end.Before(start) {
+			return fmt.Errorf("end %s is before start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+		}
+		x.End = &end
 	}
 	*tr = *x
 	return nil
@@ -64,3 +66,182 @@ func (tr *TimeRangeValue) Set(val string) error {
 func (v *TimeRangeValue) Type() string { return "timerange" }
 
 var _ pflag.Value = (*TimeRangeValue)(nil)
+
+// TimeRangesValue is a flag value for one or more time ranges, where each use of the flag adds
+// a range; see TimeRangeValue. The first use replaces the default.
+type TimeRangesValue struct {
+	Ranges []TimeRange
+	set    bool
+}
+
+// NewTimeRangesValue returns a default of a single range of 24 hours ago to now.
+func NewTimeRangesValue() TimeRangesValue {
+	tr := NewTimeRangeValue()
+	return TimeRangesValue{Ranges: []TimeRange{{Start: *tr.Start}}}
+}
+
+// String returns the ranges as <start>,[<end>] separated by spaces
+func (v *TimeRangesValue) String() string {
+	s := []string{}
+	for _, tr := range v.Ranges {
+		start := tr.Start
+		s = append(s, (&TimeRangeValue{Start: &start, End: tr.End}).String())
+	}
+	return strings.Join(s, " ")
+}
+
+func (v *TimeRangesValue) Set(val string) error {
+	tr := &TimeRangeValue{}
+	if err := tr.Set(val); err != nil {
+		return err
+	}
+	if !v.set {
+		v.Ranges = nil
+		v.set = true
+	}
+	v.Ranges = append(v.Ranges, TimeRange{Start: *tr.Start, End: tr.End})
+	return nil
+}
+
+func (v *TimeRangesValue) Type() string { return "timerange" }
+
+var _ pflag.Value = (*TimeRangesValue)(nil)
+
+// TimeValue is a flag value for a single time expression; see ParseTimeExpr.
+type TimeValue struct {
+	Time *time.Time
+}
+
+// String returns the time as RFC3339, or an empty string if not set.
+func (v *TimeValue) String() string {
+	if v.Time == nil {
+		return ""
+	}
+	return v.Time.Format(time.RFC3339)
+}
+
+func (v *TimeValue) Set(val string) error {
+	t, err := ParseTimeExpr(val, timeNow())
+	if err != nil {
+		return err
+	}
+	v.Time = &t
+	return nil
+}
+
+func (v *TimeValue) Type() string { return "time" }
+
+var _ pflag.Value = (*TimeValue)(nil)
+
+// AddCMREnvFlags adds the flags used to select the CMR environment
+func AddCMREnvFlags(flags *pflag.FlagSet) {
+	flags.String("cmr-env", "prod",
+		"CMR environment to use. One of "+strings.Join(CMREnvNames(), ", ")+". The Earthdata "+
+			"Login host used for authentication follows the environment.")
+	flags.String("cmr-url", "",
+		"CMR base url, e.g., http://localhost:3003, to use in place of the --cmr-env url.")
+}
+
+// CMREnvFromFlags resolves the CMR environment from flags added using AddCMREnvFlags.
+func CMREnvFromFlags(flags *pflag.FlagSet) (CMREnv, error) {
+	name, err := flags.GetString("cmr-env")
+	if err != nil {
+		return CMREnv{}, err
+	}
+	cmrURL, err := flags.GetString("cmr-url")
+	if err != nil {
+		return CMREnv{}, err
+	}
+	return ResolveCMREnv(name, cmrURL)
+}
+
+// spatial flags that may not be combined with --region
+var regionExclusiveFlags = []string{"polygon", "bounding-box", "circle", "point"}
+
+// AddRegionFlag adds the --region flag used to filter searches by the polygons in a file
+func AddRegionFlag(flags *pflag.FlagSet) {
+	flags.String("region", "",
+		"Filter to results overlapping the polygons in a GeoJSON, WKT, or ESRI shapefile (.shp) file. "+
+			"Coordinates must be longitude and latitude. Multiple polygons match results overlapping any "+
+			fmt.Sprintf("polygon, holes are ignored, and polygons with more than %d points are ", DefaultRegionMaxPoints)+
+			"simplified. May not be used with other spatial filters.")
+}
+
+// RegionFromFlags reads the region from the flag added using AddRegionFlag, if provided.
+func RegionFromFlags(flags *pflag.FlagSet) ([][]Point, error) {
+	path, err := flags.GetString("region")
+	if err != nil || path == "" {
+		return nil, err
+	}
+	for _, name := range regionExclusiveFlags {
+		if flags.Changed(name) {
+			return nil, fmt.Errorf("--region may not be used with --%s", name)
+		}
+	}
+	return ReadRegion(path, DefaultRegionMaxPoints)
+}
+
+// AddAttributeFlag adds the --attribute flag used to filter searches by additional attributes
+func AddAttributeFlag(flags *pflag.FlagSet) {
+	flags.StringArray("attribute", nil,
+		"Filter on a product specific additional attribute as <type>,<name>,<value> or "+
+			"<type>,<name>,<min>,<max> for a range, where either min or max may be omitted. Type is one of "+
+			strings.Join(AttributeTypes(), ", ")+". Commas in names or values may be escaped with a "+
+			"backslash. May be provided more than once to match all of the attributes. Matching "+
+			"attributes are added to the output as "+AttributeFieldPrefix+"<name>.")
+}
+
+// AttributesFromFlags parses the filters from the flag added using AddAttributeFlag
+func AttributesFromFlags(flags *pflag.FlagSet) ([]AttributeFilter, error) {
+	vals, err := flags.GetStringArray("attribute")
+	if err != nil {
+		return nil, err
+	}
+	filters := []AttributeFilter{}
+	for _, val := range vals {
+		f, err := ParseAttributeFilter(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --attribute: %w", err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// AddCacheFlags adds the flags controlling the response cache, where ttl is the default
+// --cache-ttl
+func AddCacheFlags(flags *pflag.FlagSet, ttl time.Duration) {
+	flags.Bool("no-cache", false, "Do not use or update the CMR response cache.")
+	flags.Bool("refresh", false,
+		"Ignore cached CMR responses, requesting fresh results and updating the cache with them.")
+	flags.Duration("cache-ttl", ttl,
+		"How long cached CMR responses are used. See the cache command to list or purge the cache.")
+}
+
+// ResponseCacheFromFlags creates the response cache from the flags added using AddCacheFlags.
+// The cache is nil if caching is disabled.
+func ResponseCacheFromFlags(flags *pflag.FlagSet) (*ResponseCache, error) {
+	noCache, err := flags.GetBool("no-cache")
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := flags.GetBool("refresh")
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := flags.GetDuration("cache-ttl")
+	if err != nil {
+		return nil, err
+	}
+	if noCache && refresh {
+		return nil, fmt.Errorf("--no-cache may not be used with --refresh")
+	}
+	if noCache || ttl <= 0 {
+		return nil, nil
+	}
+	dir, err := DefaultResponseCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("resolving cache dir: %w", err)
+	}
+	return NewResponseCache(dir, ttl).WithRefresh(refresh), nil
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
 )
 
@@ -23,4 +24,106 @@ func TestTimeRangeValue(t *testing.T) {
 	require.NoError(t, tr.Set("1970-01-01T00:00:00Z,1970-01-01T00:00:00Z"))
 	require.True(t, tr.Start.Equal(time.Unix(0, 0).UTC()))
 	require.True(t, tr.End.Equal(time.Unix(0, 0).UTC()))
+	require.Equal(t, "1970-01-01T00:00:00Z,1970-01-01T00:00:00Z", tr.String())
+
+	require.Equal(t, "", (&TimeRangeValue{}).String(), "unset range has no string value")
+
+	t.Run("expressions", func(t *testing.T) {
+		now := time.Date(2023, 4, 25, 13, 0, 0, 0, time.UTC)
+		timeNow = func() time.Time { return now }
+		defer func() { timeNow = time.Now }()
+
+		tr := &TimeRangeValue{}
+		require.NoError(t, tr.Set("2023-001,yesterday"))
+		require.Equal(t, "2023-01-01T00:00:00Z,2023-04-24T00:00:00Z", tr.String())
+
+		require.NoError(t, tr.Set("-6h,"))
+		require.Equal(t, "2023-04-25T07:00:00Z,", tr.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tr := &TimeRangeValue{}
+		err := tr.Set(",2023-01-01")
+		require.ErrorContains(t, err, "start time is required")
+		err = tr.Set("2023-01-02,2023-01-01")
+		require.ErrorContains(t, err, "before start")
+		err = tr.Set("2023-01-01")
+		require.Error(t, err)
+		require.Nil(t, tr.Start, "value is not changed on error")
+	})
+
+	def := NewTimeRangeValue()
+	require.Nil(t, def.End)
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), *def.Start, time.Minute)
+}
+
+func TestRegionFromFlags(t *testing.T) {
+	newFlags := func(args ...string) *pflag.FlagSet {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.Float64Slice("polygon", nil, "")
+		AddRegionFlag(flags)
+		require.NoError(t, flags.Parse(args))
+		return flags
+	}
+	path := writeRegionFile(t, "region.wkt", []byte("POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))"))
+
+	region, err := RegionFromFlags(newFlags())
+	require.NoError(t, err)
+	require.Nil(t, region, "region is optional")
+
+	region, err = RegionFromFlags(newFlags("--region", path))
+	require.NoError(t, err)
+	require.Equal(t, [][]Point{ccwSquare}, region)
+
+	_, err = RegionFromFlags(newFlags("--region", path, "--polygon", "0,0,1,0,1,1,0,0"))
+	require.Error(t, err, "region may not be combined with other spatial flags")
+}
+
+func TestTimeValue(t *testing.T) {
+	v := &TimeValue{}
+	require.Equal(t, "", v.String())
+	require.Error(t, v.Set("a"))
+	require.NoError(t, v.Set("2023115"))
+	require.Equal(t, "2023-04-25T00:00:00Z", v.String())
+}
+
+func TestTimeRangesValue(t *testing.T) {
+	v := NewTimeRangesValue()
+	require.Len(t, v.Ranges, 1, "default is a single range")
+
+	require.NoError(t, v.Set("1970-01-01,1970-01-02"))
+	require.NoError(t, v.Set("1971-01-01,"))
+	require.Equal(t, "1970-01-01T00:00:00Z,1970-01-02T00:00:00Z 1971-01-01T00:00:00Z,", v.String(),
+		"first use replaces the default")
+
+	require.Error(t, v.Set("x"))
+	require.Len(t, v.Ranges, 2)
+}
+
+func TestResponseCacheFromFlags(t *testing.T) {
+	newFlags := func(args ...string) *pflag.FlagSet {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		AddCacheFlags(flags, time.Hour)
+		require.NoError(t, flags.Parse(args))
+		return flags
+	}
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	cache, err := ResponseCacheFromFlags(newFlags())
+	require.NoError(t, err)
+	require.NotNil(t, cache)
+	require.Equal(t, time.Hour, cache.ttl)
+	require.False(t, cache.refresh)
+
+	cache, err = ResponseCacheFromFlags(newFlags("--refresh", "--cache-ttl", "10m"))
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, cache.ttl)
+	require.True(t, cache.refresh)
+
+	cache, err = ResponseCacheFromFlags(newFlags("--no-cache"))
+	require.NoError(t, err)
+	require.Nil(t, cache)
+
+	_, err = ResponseCacheFromFlags(newFlags("--no-cache", "--refresh"))
+	require.Error(t, err)
 }
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// fletcher implements the Fletcher checksums, where data is summed as little-endian words of
+// wordSize bytes modulo 2^(8*wordSize)-1. A trailing partial word is zero padded.
+type fletcher struct {
+	wordSize int
+	modulus  uint64
+	sum1     uint64
+	sum2     uint64
+	// buffered bytes of a partial word
+	partial []byte
+}
+
+func newFletcher16() *fletcher { return &fletcher{wordSize: 1, modulus: 0xff} }
+
+func newFletcher32() *fletcher { return &fletcher{wordSize: 2, modulus: 0xffff} }
+
+func newFletcher64() *fletcher { return &fletcher{wordSize: 4, modulus: 0xffffffff} }
+
+var _ hash.Hash = (*fletcher)(nil)
+
+func (f *fletcher) add(word uint64) {
+	f.sum1 = (f.sum1 + word) % f.modulus
+	f.sum2 = (f.sum2 + f.sum1) % f.modulus
+}
+
+func word(b []byte) uint64 {
+	var w uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		w = w<<8 | uint64(b[i])
+	}
+	return w
+}
+
+func (f *fletcher) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(f.partial) > 0 {
+		need := f.wordSize - len(f.partial)
+		if len(p) < need {
+			f.partial = append(f.partial, p...)
+			return n, nil
+		}
+		f.partial = append(f.partial, p[:need]...)
+		f.add(word(f.partial))
+		f.partial = f.partial[:0]
+		p = p[need:]
+	}
+	for len(p) >= f.wordSize {
+		f.add(word(p[:f.wordSize]))
+		p = p[f.wordSize:]
+	}
+	f.partial = append(f.partial, p...)
+	return n, nil
+}
+
+func (f *fletcher) Sum(b []byte) []byte {
+	sum1, sum2 := f.sum1, f.sum2
+	if len(f.partial) > 0 {
+		padded := make([]byte, f.wordSize)
+		copy(padded, f.partial)
+		sum1 = (sum1 + word(padded)) % f.modulus
+		sum2 = (sum2 + sum1) % f.modulus
+	}
+	bits := uint(8 * f.wordSize)
+	sum := sum2<<bits | sum1
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, sum)
+	return append(b, buf[8-f.Size():]...)
+}
+
+func (f *fletcher) Reset() {
+	f.sum1, f.sum2 = 0, 0
+	f.partial = f.partial[:0]
+}
+
+func (f *fletcher) Size() int { return 2 * f.wordSize }
+
+func (f *fletcher) BlockSize() int { return f.wordSize }
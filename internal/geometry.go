@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"github.com/tidwall/gjson"
+)
+
+// Point is a geographic coordinate in degrees
+type Point struct {
+	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat"`
+}
+
+// BoundingRectangle is a lat/lon aligned rectangle in degrees. West may be greater than East if
+// the rectangle crosses the antimeridian.
+type BoundingRectangle struct {
+	West  float64 `json:"west"`
+	North float64 `json:"north"`
+	East  float64 `json:"east"`
+	South float64 `json:"south"`
+}
+
+// Ring returns the closed, counter-clockwise, boundary of the rectangle.
+func (r BoundingRectangle) Ring() []Point {
+	return []Point{
+		{r.West, r.South},
+		{r.East, r.South},
+		{r.East, r.North},
+		{r.West, r.North},
+		{r.West, r.South},
+	}
+}
+
+// GPolygon is a polygon with a counter-clockwise outer boundary and optional clockwise holes,
+// i.e., exclusive zones.
+type GPolygon struct {
+	Boundary []Point   `json:"boundary"`
+	Holes    [][]Point `json:"holes,omitempty"`
+}
+
+// Geometry is the horizontal spatial extent of a granule. A granule may have any number of
+// each type of shape.
+type Geometry struct {
+	Points             []Point             `json:"points,omitempty"`
+	BoundingRectangles []BoundingRectangle `json:"bounding_rectangles,omitempty"`
+	GPolygons          []GPolygon          `json:"gpolygons,omitempty"`
+	Lines              [][]Point           `json:"lines,omitempty"`
+}
+
+// IsEmpty returns true if the geometry contains no shapes
+func (g Geometry) IsEmpty() bool {
+	return len(g.Points) == 0 && len(g.BoundingRectangles) == 0 && len(g.GPolygons) == 0 &&
+		len(g.Lines) == 0
+}
+
+func decodePoints(zult gjson.Result) []Point {
+	points := []Point{}
+	for _, point := range zult.Array() {
+		points = append(points, decodePoint(point))
+	}
+	return points
+}
+
+func decodePoint(zult gjson.Result) Point {
+	return Point{Lon: zult.Get("Longitude").Float(), Lat: zult.Get("Latitude").Float()}
+}
+
+// decodeGeometry decodes a UMM-G HorizontalSpatialDomain.Geometry. Orbit based spatial
+// domains are not supported.
+func decodeGeometry(zult gjson.Result) Geometry {
+	geom := Geometry{}
+	for _, point := range zult.Get("Points").Array() {
+		geom.Points = append(geom.Points, decodePoint(point))
+	}
+	for _, rect := range zult.Get("BoundingRectangles").Array() {
+		geom.BoundingRectangles = append(geom.BoundingRectangles, BoundingRectangle{
+			West:  rect.Get("WestBoundingCoordinate").Float(),
+			North: rect.Get("NorthBoundingCoordinate").Float(),
+			East:  rect.Get("EastBoundingCoordinate").Float(),
+			South: rect.Get("SouthBoundingCoordinate").Float(),
+		})
+	}
+	for _, polygon := range zult.Get("GPolygons").Array() {
+		gpoly := GPolygon{Boundary: decodePoints(polygon.Get("Boundary.Points"))}
+		for _, hole := range polygon.Get("ExclusiveZone.Boundaries").Array() {
+			gpoly.Holes = append(gpoly.Holes, decodePoints(hole.Get("Points")))
+		}
+		geom.GPolygons = append(geom.GPolygons, gpoly)
+	}
+	for _, line := range zult.Get("Lines").Array() {
+		geom.Lines = append(geom.Lines, decodePoints(line.Get("Points")))
+	}
+	return geom
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestDecodeGeometry(t *testing.T) {
+	doc := `{
+		"Points": [{"Longitude": 1, "Latitude": 2}],
+		"BoundingRectangles": [{
+			"WestBoundingCoordinate": -10, "NorthBoundingCoordinate": 20,
+			"EastBoundingCoordinate": 10, "SouthBoundingCoordinate": -20
+		}],
+		"GPolygons": [{
+			"Boundary": {"Points": [
+				{"Longitude": 0, "Latitude": 0}, {"Longitude": 10, "Latitude": 0},
+				{"Longitude": 10, "Latitude": 10}, {"Longitude": 0, "Latitude": 0}
+			]},
+			"ExclusiveZone": {"Boundaries": [{"Points": [
+				{"Longitude": 1, "Latitude": 1}, {"Longitude": 2, "Latitude": 2},
+				{"Longitude": 2, "Latitude": 1}, {"Longitude": 1, "Latitude": 1}
+			]}]}
+		}],
+		"Lines": [{"Points": [{"Longitude": 5, "Latitude": 6}, {"Longitude": 7, "Latitude": 8}]}]
+	}`
+	require.True(t, gjson.Valid(doc))
+
+	geom := decodeGeometry(gjson.Parse(doc))
+
+	require.Equal(t, Geometry{
+		Points:             []Point{{1, 2}},
+		BoundingRectangles: []BoundingRectangle{{West: -10, North: 20, East: 10, South: -20}},
+		GPolygons: []GPolygon{{
+			Boundary: []Point{{0, 0}, {10, 0}, {10, 10}, {0, 0}},
+			Holes:    [][]Point{{{1, 1}, {2, 2}, {2, 1}, {1, 1}}},
+		}},
+		Lines: [][]Point{{{5, 6}, {7, 8}}},
+	}, geom)
+	require.False(t, geom.IsEmpty())
+
+	t.Run("missing is empty", func(t *testing.T) {
+		require.True(t, decodeGeometry(gjson.Parse(`{}`)).IsEmpty())
+	})
+}
+
+func TestBoundingRectangleRing(t *testing.T) {
+	ring := BoundingRectangle{West: -10, North: 20, East: 10, South: -20}.Ring()
+	require.Equal(t, []Point{{-10, -20}, {10, -20}, {10, 20}, {-10, 20}, {-10, -20}}, ring)
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,9 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"time"
-)
 
-var defaultHoldingsURL = "https://cmr.earthdata.nasa.gov/search/provider_holdings.json"
+	"github.com/bmflynn/cmrfetch/internal/log"
+)
 
 type ProviderCollection struct{}
 
@@ -69,72 +70,60 @@ func readHoldings(r io.Reader) ([]Provider, error) {
 	return zult, nil
 }
 
-func getCachedProviderHoldings() ([]Provider, time.Time, error) {
+// HoldingsCacheTTL is the default time provider holdings are cached, which are large and
+// change infrequently
+const HoldingsCacheTTL = 30 * 24 * time.Hour
+
+// removeLegacyHoldingsCache removes the provider holdings cache files used before holdings
+// were kept in the response cache, which are otherwise never cleaned up.
+func removeLegacyHoldingsCache() {
 	dir, err := os.UserCacheDir()
 	if err != nil {
-		return nil, time.Time{}, err
-	}
-	dir = filepath.Join(dir, "cmrfetch")
-	if !Exists(dir) {
-		return nil, time.Time{}, nil
-	}
-	if !IsDir(dir) {
-		return nil, time.Time{}, fmt.Errorf("expected %s to be a dir", dir)
-	}
-	fpath := filepath.Join(dir, "provider_holdings.json")
-	if !Exists(fpath) {
-		return nil, time.Time{}, nil
+		return
 	}
-	f, err := os.Open(filepath.Join(dir, "provider_holdings.json"))
+	files, err := filepath.Glob(filepath.Join(dir, "cmrfetch", "provider_holdings*.json"))
 	if err != nil {
-		return nil, time.Time{}, err
+		return
 	}
-	fi, err := f.Stat()
-	if err != nil {
-		return nil, time.Time{}, err
+	for _, fpath := range files {
+		if err := os.Remove(fpath); err != nil {
+			log.Debug("removing legacy holdings cache: %s", err)
+		} else {
+			log.Debug("removed legacy holdings cache %s", fpath)
+		}
 	}
-
-	var providers []Provider
-	return providers, fi.ModTime(), json.NewDecoder(f).Decode(&providers)
 }
 
-func writeCachedProviderHoldings(providers []Provider) error {
-	dir, err := os.UserCacheDir()
-	if err != nil {
-		return err
-	}
-	dir = filepath.Join(dir, "cmrfetch")
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("making dir: %w", err)
-	}
-	f, err := os.Create(filepath.Join(dir, "provider_holdings.json"))
-	if err != nil {
-		return err
+// GetProviderHoldings returns the provider holdings for the CMR environment, using the
+// holdings in cache if available. A nil cache disables caching.
+func GetProviderHoldings(env CMREnv, cache *ResponseCache) ([]Provider, error) {
+	removeLegacyHoldingsCache()
+	url := env.SearchURL() + "/provider_holdings.json"
+	if _, body, ok := cache.Get(url, ""); ok {
+		if providers, err := readHoldings(bytes.NewReader(body)); err == nil {
+			return providers, nil
+		}
 	}
-	return json.NewEncoder(f).Encode(providers)
-}
 
-func GetProviderHoldings() ([]Provider, error) {
-	providers, mtime, err := getCachedProviderHoldings()
+	resp, err := http.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("loading cached holdings: %w", err)
+		return nil, err
 	}
-	if len(providers) > 0 && time.Since(mtime) < time.Hour*24*30 {
-		return providers, nil
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
 	}
-	resp, err := http.Get(defaultHoldingsURL)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("reading response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	providers, err = readHoldings(resp.Body)
+	providers, err := readHoldings(bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
-	// intentionally ignoring error
-	if err := writeCachedProviderHoldings(providers); err != nil {
-		return nil, fmt.Errorf("writing to cache dir: %w", err)
+	if err := cache.Put(url, "", resp.Header, body); err != nil {
+		log.Printf("WARNING: failed to cache provider holdings: %s", err)
 	}
-	return providers, err
+	return providers, nil
 }
@@ -8,7 +8,6 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -68,102 +67,13 @@ func testCacheDir(t *testing.T) (string, func()) {
 	}
 }
 
-func Test_getCachedProviderHoldngs(t *testing.T) {
-	t.Run("cache dir missing is ok", func(t *testing.T) {
-		_, cleanup := testCacheDir(t)
-		defer cleanup()
-
-		zult, mtime, err := getCachedProviderHoldings()
-		t.Logf("%#v", zult)
-		require.Nil(t, zult, fmt.Sprintf("arr should be nil: %#v", zult))
-		require.True(t, mtime.Equal(time.Time{}))
-		require.Nil(t, err)
-	})
-
-	t.Run("not a dir is err", func(t *testing.T) {
-		dir, cleanup := testCacheDir(t)
-		defer cleanup()
-		err := os.WriteFile(filepath.Join(dir, "cmrfetch"), nil, 0o644)
-		require.NoError(t, err)
-
-		zult, mtime, err := getCachedProviderHoldings()
-		require.Nil(t, zult)
-		require.True(t, mtime.Equal(time.Time{}))
-		require.NotNil(t, err)
-	})
-
-	t.Run("cache file missing is ok", func(t *testing.T) {
-		dir, cleanup := testCacheDir(t)
-		defer cleanup()
-		err := os.MkdirAll(filepath.Join(dir, "cmrfetch"), 0o755)
-		require.NoError(t, err)
-
-		zult, mtime, err := getCachedProviderHoldings()
-		require.Nil(t, zult)
-		require.True(t, mtime.Equal(time.Time{}))
-		require.NoError(t, err)
-	})
-
-	t.Run("fail to open is err", func(t *testing.T) {
-		dir, cleanup := testCacheDir(t)
-		defer cleanup()
-		err := os.MkdirAll(filepath.Join(dir, "cmrfetch"), 0o755)
-		require.NoError(t, err)
-		fpath := filepath.Join(dir, "cmrfetch", "provider_holdings.json")
-		require.NoError(t, os.WriteFile(fpath, nil, 0o000))
-
-		zult, mtime, err := getCachedProviderHoldings()
-		require.Nil(t, zult)
-		require.True(t, mtime.Equal(time.Time{}))
-		require.Error(t, err)
-	})
-
-	t.Run("nominal", func(t *testing.T) {
-		dir, cleanup := testCacheDir(t)
-		defer cleanup()
-		err := os.MkdirAll(filepath.Join(dir, "cmrfetch"), 0o755)
-		require.NoError(t, err)
-		fpath := filepath.Join(dir, "cmrfetch", "provider_holdings.json")
-		require.NoError(t, os.WriteFile(fpath, []byte(`[]`), 0o644))
-
-		zult, mtime, err := getCachedProviderHoldings()
-		require.Len(t, zult, 0)
-		require.Less(t, time.Since(mtime), time.Minute)
-		require.NoError(t, err)
-	})
-}
-
-func Test_writeCachedProviderHoldings(t *testing.T) {
-	dir, cleanup := testCacheDir(t)
-	defer cleanup()
-
-	providers := []Provider{
-		{
-			ID: "PROVIDER1",
-			Collections: []CollectionItem{
-				{
-					ConceptID: "C1",
-					Title:     "TITLE",
-				},
-			},
-			GranuleCount: 0,
-		},
-	}
-
-	err := writeCachedProviderHoldings(providers)
-	require.NoError(t, err)
-
-	fpath := filepath.Join(dir, "cmrfetch", "provider_holdings.json")
-	fi, err := os.Stat(fpath)
-	require.NoError(t, err)
-	require.Greater(t, fi.Size(), int64(0))
-}
-
 func TestGetProviderHoldings(t *testing.T) {
 	dir, cleanup := testCacheDir(t)
 	defer cleanup()
 
+	requests := 0
 	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
 		_, err := w.Write([]byte(`
 [
   {
@@ -188,17 +98,42 @@ func TestGetProviderHoldings(t *testing.T) {
 		require.NoError(t, err)
 	}))
 	defer svr.Close()
-	origURL := defaultHoldingsURL
-	defaultHoldingsURL = fmt.Sprintf("http://%s/", svr.Listener.Addr())
-	defer func() {
-		defaultHoldingsURL = origURL
-	}()
+	env := CMREnv{Name: "test", URL: fmt.Sprintf("http://%s", svr.Listener.Addr())}
+
+	cache := NewResponseCache(filepath.Join(dir, "responses"), HoldingsCacheTTL)
 
-	providers, err := GetProviderHoldings()
+	legacy := []string{
+		filepath.Join(dir, "cmrfetch", "provider_holdings.json"),
+		filepath.Join(dir, "cmrfetch", "provider_holdings.uat.json"),
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "cmrfetch"), 0o755))
+	for _, fpath := range legacy {
+		require.NoError(t, os.WriteFile(fpath, []byte("[]"), 0o644))
+	}
+
+	providers, err := GetProviderHoldings(env, cache)
 	require.NoError(t, err)
 	require.Len(t, providers, 2)
+	require.Equal(t, 1, requests)
+	for _, fpath := range legacy {
+		require.NoFileExists(t, fpath, "legacy holdings cache should be removed")
+	}
 
-	fpath := filepath.Join(dir, "cmrfetch", "provider_holdings.json")
+	fpath := filepath.Join(dir, "responses", ResponseCacheKey(env.SearchURL()+"/provider_holdings.json", ""))
 	_, err = os.Stat(fpath)
 	require.NoError(t, err)
+
+	t.Run("cached", func(t *testing.T) {
+		providers, err := GetProviderHoldings(env, cache)
+		require.NoError(t, err)
+		require.Len(t, providers, 2)
+		require.Equal(t, 1, requests, "expected holdings from cache")
+	})
+
+	t.Run("no cache", func(t *testing.T) {
+		providers, err := GetProviderHoldings(env, nil)
+		require.NoError(t, err)
+		require.Len(t, providers, 2)
+		require.Equal(t, 2, requests)
+	})
 }
@@ -1,6 +1,10 @@
 package internal
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
 
 func CanWrite(path string) bool {
 	if fi, err := os.Stat(path); err == nil {
@@ -20,3 +24,29 @@ func IsDir(path string) bool {
 	}
 	return false
 }
+
+// WriteFileAtomic writes dat to path by writing a temp file in the same directory and renaming
+// it so concurrent readers never see a partial file. Parent directories are created as needed.
+func WriteFileAtomic(path string, dat []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("making dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(dat); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"io"
 	"log"
 	"os"
 )
@@ -16,6 +17,12 @@ func SetVerbose(b bool) {
 	verbose = b
 }
 
+// SetOutput sets the destination for all log output, which is stderr by default.
+func SetOutput(w io.Writer) {
+	debugLogger.SetOutput(w)
+	infoLogger.SetOutput(w)
+}
+
 func Debug(s string, args ...any) {
 	if verbose {
 		debugLogger.Printf(s, args...)
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var manifestBucket = []byte("granules")
+
+// ManifestRecord is a granule file that has been downloaded
+type ManifestRecord struct {
+	ConceptID   string    `json:"concept_id"`
+	RevisionID  string    `json:"revision_id"`
+	Name        string    `json:"name"`
+	Collection  string    `json:"collection"`
+	URL         string    `json:"url"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	ChecksumAlg string    `json:"checksum_alg"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+func manifestKey(conceptID, name string) []byte {
+	return []byte(conceptID + "/" + name)
+}
+
+// Manifest is a local database of downloaded granule files. Records are keyed by granule
+// concept id and file name, and contain the revision id of the granule that was downloaded
+// so the manifest may be used to determine if a granule needs to be downloaded without the
+// file having to exist locally.
+//
+// Only a single process may have a manifest open at a time.
+type Manifest struct {
+	db *bolt.DB
+}
+
+// OpenManifest opens, or creates, the manifest database at path.
+func OpenManifest(path string) (*Manifest, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			return nil, fmt.Errorf("opening manifest %s: in use by another process", path)
+		}
+		return nil, fmt.Errorf("opening manifest %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(manifestBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing manifest %s: %w", path, err)
+	}
+	return &Manifest{db: db}, nil
+}
+
+func (m *Manifest) Close() error {
+	return m.db.Close()
+}
+
+// Get returns the record for the granule file, if it exists.
+func (m *Manifest) Get(conceptID, name string) (ManifestRecord, bool, error) {
+	var rec ManifestRecord
+	var found bool
+	err := m.db.View(func(tx *bolt.Tx) error {
+		dat := tx.Bucket(manifestBucket).Get(manifestKey(conceptID, name))
+		if dat == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(dat, &rec)
+	})
+	return rec, found, err
+}
+
+// Put adds or replaces the record for a granule file.
+func (m *Manifest) Put(rec ManifestRecord) error {
+	if rec.ConceptID == "" || rec.Name == "" {
+		return fmt.Errorf("manifest records require a concept id and name")
+	}
+	dat, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return m.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(manifestBucket).Put(manifestKey(rec.ConceptID, rec.Name), dat)
+	})
+}
+
+// Each calls fn for every record, ordered by concept id and name. Iteration stops at the first
+// error returned by fn.
+func (m *Manifest) Each(fn func(ManifestRecord) error) error {
+	return m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(manifestBucket).ForEach(func(k, v []byte) error {
+			var rec ManifestRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("decoding manifest record %s: %w", k, err)
+			}
+			return fn(rec)
+		})
+	})
+}
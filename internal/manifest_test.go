@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "manifest.db")
+
+	manifest, err := OpenManifest(fpath)
+	require.NoError(t, err)
+
+	_, found, err := manifest.Get("G1-P", "a.nc")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	fetched := time.Date(2023, 4, 27, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, manifest.Put(ManifestRecord{ConceptID: "G2-P", RevisionID: "1", Name: "b.nc"}))
+	require.NoError(t, manifest.Put(ManifestRecord{ConceptID: "G1-P", RevisionID: "1", Name: "a.nc"}))
+	require.NoError(t, manifest.Put(ManifestRecord{
+		ConceptID:  "G1-P",
+		RevisionID: "2",
+		Name:       "a.nc",
+		Path:       "/data/a.nc",
+		Size:       10,
+		FetchedAt:  fetched,
+	}))
+	require.Error(t, manifest.Put(ManifestRecord{Name: "c.nc"}), "concept id is required")
+	require.NoError(t, manifest.Close())
+
+	t.Run("only one process at a time", func(t *testing.T) {
+		manifest, err := OpenManifest(fpath)
+		require.NoError(t, err)
+		defer manifest.Close()
+
+		_, err = OpenManifest(fpath)
+		require.Error(t, err)
+	})
+
+	manifest, err = OpenManifest(fpath)
+	require.NoError(t, err)
+	defer manifest.Close()
+
+	rec, found, err := manifest.Get("G1-P", "a.nc")
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "2", rec.RevisionID, "expected put to replace existing record")
+	require.Equal(t, "/data/a.nc", rec.Path)
+	require.True(t, rec.FetchedAt.Equal(fetched))
+
+	names := []string{}
+	require.NoError(t, manifest.Each(func(rec ManifestRecord) error {
+		names = append(names, rec.Name)
+		return nil
+	}))
+	require.Equal(t, []string{"a.nc", "b.nc"}, names)
+}
@@ -0,0 +1,560 @@
+package internal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/bmflynn/cmrfetch/internal/log"
+)
+
+// DefaultRegionMaxPoints is the default maximum number of points in a region polygon. Polygons
+// with more points are simplified to keep search urls within CMR limits.
+const DefaultRegionMaxPoints = 100
+
+// ReadRegion reads the polygons from a GeoJSON, WKT, or ESRI shapefile. Files with a .shp
+// extension are read as shapefiles, otherwise the format is determined from the content.
+//
+// Polygons are returned as closed counter-clockwise rings suitable for use as CMR polygon
+// search params. Holes are not supported by CMR and are ignored. Polygons with more than
+// maxPoints points are simplified.
+func ReadRegion(path string, maxPoints int) ([][]Point, error) {
+	var polygons [][][]Point
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".shp") {
+		polygons, err = readShapefileRegion(path)
+	} else {
+		var dat []byte
+		dat, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s := strings.TrimSpace(string(dat))
+		if strings.HasPrefix(s, "{") {
+			polygons, err = parseGeoJSONRegion([]byte(s))
+		} else {
+			polygons, err = parseWKTRegion(s)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading region %s: %w", path, err)
+	}
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("reading region %s: no polygons found", path)
+	}
+
+	rings := [][]Point{}
+	for i, polygon := range polygons {
+		if len(polygon) > 1 {
+			log.Printf("WARNING: ignoring %d hole(s) in region polygon %d, holes are not supported by CMR",
+				len(polygon)-1, i+1)
+		}
+		ring, err := normalizeRing(polygon[0], maxPoints)
+		if err != nil {
+			return nil, fmt.Errorf("reading region %s: polygon %d: %w", path, i+1, err)
+		}
+		rings = append(rings, ring)
+	}
+	return rings, nil
+}
+
+// addRegionParams adds polygon search params for each region polygon. A search matches any
+// of the polygons.
+func addRegionParams(query url.Values, polygons [][]Point) {
+	for _, ring := range polygons {
+		vals := []float64{}
+		for _, p := range ring {
+			vals = append(vals, p.Lon, p.Lat)
+		}
+		query.Add("polygon[]", joinFloats(vals))
+	}
+	if len(polygons) > 1 {
+		query.Set("options[spatial][or]", "true")
+	}
+}
+
+// signedArea returns the planar area of ring, which is positive if the ring is
+// counter-clockwise.
+func signedArea(ring []Point) float64 {
+	area := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		area += ring[i].Lon*ring[i+1].Lat - ring[i+1].Lon*ring[i].Lat
+	}
+	return area / 2
+}
+
+func reversed(ring []Point) []Point {
+	rev := make([]Point, len(ring))
+	for i, p := range ring {
+		rev[len(ring)-1-i] = p
+	}
+	return rev
+}
+
+// normalizeRing closes ring, removes repeated points, simplifies it to at most maxPoints points,
+// and makes it counter-clockwise.
+func normalizeRing(ring []Point, maxPoints int) ([]Point, error) {
+	norm := []Point{}
+	for _, p := range ring {
+		if len(norm) > 0 && norm[len(norm)-1] == p {
+			continue
+		}
+		norm = append(norm, p)
+	}
+	if len(norm) > 0 && norm[0] != norm[len(norm)-1] {
+		norm = append(norm, norm[0])
+	}
+	if len(norm) < 4 {
+		return nil, fmt.Errorf("polygons must have at least 3 distinct points")
+	}
+	if maxPoints > 0 && len(norm) > maxPoints {
+		simple, err := simplifyRing(norm, maxPoints)
+		if err != nil {
+			return nil, err
+		}
+		log.Debug("simplified region polygon from %d to %d points", len(norm), len(simple))
+		norm = simple
+	}
+	switch area := signedArea(norm); {
+	case area == 0:
+		return nil, fmt.Errorf("polygon has no area")
+	case area < 0:
+		norm = reversed(norm)
+	}
+	return norm, nil
+}
+
+// simplifyRing simplifies a closed ring to at most maxPoints points using Douglas-Peucker with
+// an increasing tolerance.
+func simplifyRing(ring []Point, maxPoints int) ([]Point, error) {
+	if maxPoints < 4 {
+		return nil, fmt.Errorf("polygons require at least 4 points, max points is %d", maxPoints)
+	}
+	minLon, maxLon, minLat, maxLat := ring[0].Lon, ring[0].Lon, ring[0].Lat, ring[0].Lat
+	for _, p := range ring {
+		minLon, maxLon = math.Min(minLon, p.Lon), math.Max(maxLon, p.Lon)
+		minLat, maxLat = math.Min(minLat, p.Lat), math.Max(maxLat, p.Lat)
+	}
+	tolerance := math.Hypot(maxLon-minLon, maxLat-minLat) * 1e-4
+	for {
+		simple := douglasPeucker(ring, tolerance)
+		if len(simple) < 4 {
+			return nil, fmt.Errorf("polygon could not be simplified to %d points", maxPoints)
+		}
+		if len(simple) <= maxPoints {
+			return simple, nil
+		}
+		tolerance *= 1.5
+	}
+}
+
+// segmentDistance returns the distance from p to the segment a-b
+func segmentDistance(p, a, b Point) float64 {
+	dx, dy := b.Lon-a.Lon, b.Lat-a.Lat
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.Lon-a.Lon, p.Lat-a.Lat)
+	}
+	t := ((p.Lon-a.Lon)*dx + (p.Lat-a.Lat)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.Lon-(a.Lon+t*dx), p.Lat-(a.Lat+t*dy))
+}
+
+func douglasPeucker(points []Point, tolerance float64) []Point {
+	if len(points) < 3 {
+		return points
+	}
+	first, last := points[0], points[len(points)-1]
+	idx, dist := 0, 0.0
+	for i := 1; i < len(points)-1; i++ {
+		if d := segmentDistance(points[i], first, last); d > dist {
+			idx, dist = i, d
+		}
+	}
+	if dist <= tolerance {
+		return []Point{first, last}
+	}
+	left := douglasPeucker(points[:idx+1], tolerance)
+	right := douglasPeucker(points[idx:], tolerance)
+	return append(left[:len(left)-1], right...)
+}
+
+func pointsFromPositions(positions [][]float64) ([]Point, error) {
+	points := []Point{}
+	for _, pos := range positions {
+		if len(pos) < 2 {
+			return nil, fmt.Errorf("positions must have at least 2 values")
+		}
+		points = append(points, Point{Lon: pos[0], Lat: pos[1]})
+	}
+	return points, nil
+}
+
+func polygonFromPositions(rings [][][]float64) ([][]Point, error) {
+	polygon := [][]Point{}
+	for _, ring := range rings {
+		points, err := pointsFromPositions(ring)
+		if err != nil {
+			return nil, err
+		}
+		polygon = append(polygon, points)
+	}
+	if len(polygon) == 0 {
+		return nil, fmt.Errorf("polygon has no rings")
+	}
+	return polygon, nil
+}
+
+// parseGeoJSONRegion returns the polygons from a GeoJSON geometry, feature, or feature
+// collection. Each polygon is returned as its outer ring followed by any holes.
+func parseGeoJSONRegion(dat []byte) ([][][]Point, error) {
+	var obj struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometry    json.RawMessage   `json:"geometry"`
+		Geometries  []json.RawMessage `json:"geometries"`
+		Features    []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(dat, &obj); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	var children []json.RawMessage
+	switch obj.Type {
+	case "FeatureCollection":
+		children = obj.Features
+	case "Feature":
+		if len(obj.Geometry) == 0 || string(obj.Geometry) == "null" {
+			return nil, nil
+		}
+		children = []json.RawMessage{obj.Geometry}
+	case "GeometryCollection":
+		children = obj.Geometries
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid Polygon coordinates: %w", err)
+		}
+		polygon, err := polygonFromPositions(coords)
+		if err != nil {
+			return nil, err
+		}
+		return [][][]Point{polygon}, nil
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(obj.Coordinates, &coords); err != nil {
+			return nil, fmt.Errorf("invalid MultiPolygon coordinates: %w", err)
+		}
+		polygons := [][][]Point{}
+		for _, c := range coords {
+			polygon, err := polygonFromPositions(c)
+			if err != nil {
+				return nil, err
+			}
+			polygons = append(polygons, polygon)
+		}
+		return polygons, nil
+	default:
+		return nil, fmt.Errorf("unsupported GeoJSON type %q, regions must be polygons", obj.Type)
+	}
+
+	polygons := [][][]Point{}
+	for _, child := range children {
+		zult, err := parseGeoJSONRegion(child)
+		if err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, zult...)
+	}
+	return polygons, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space character, or 0 at the end of input
+func (p *wktParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *wktParser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("expected %q at offset %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// word returns the next run of letters, uppercased
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && unicode.IsLetter(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+func (p *wktParser) number() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number at offset %d", start)
+	}
+	return v, nil
+}
+
+// list parses a parenthesized, comma separated list calling fn for each item
+func (p *wktParser) list(fn func() error) error {
+	if err := p.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := fn(); err != nil {
+			return err
+		}
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	return p.expect(')')
+}
+
+func (p *wktParser) ring() ([]Point, error) {
+	ring := []Point{}
+	err := p.list(func() error {
+		// Z and M values, if any, are ignored
+		vals := []float64{}
+		for c := p.peek(); c != ',' && c != ')' && c != 0; c = p.peek() {
+			v, err := p.number()
+			if err != nil {
+				return err
+			}
+			vals = append(vals, v)
+		}
+		if len(vals) < 2 {
+			return fmt.Errorf("points must have at least 2 values at offset %d", p.pos)
+		}
+		ring = append(ring, Point{Lon: vals[0], Lat: vals[1]})
+		return nil
+	})
+	return ring, err
+}
+
+func (p *wktParser) polygon() ([][]Point, error) {
+	polygon := [][]Point{}
+	err := p.list(func() error {
+		ring, err := p.ring()
+		polygon = append(polygon, ring)
+		return err
+	})
+	return polygon, err
+}
+
+// geometry parses a tagged geometry, returning its polygons
+func (p *wktParser) geometry() ([][][]Point, error) {
+	typ := p.word()
+	// Dimension, e.g., POLYGON Z, or EMPTY
+	dim := p.word()
+	if dim == "EMPTY" {
+		return nil, nil
+	}
+	if dim != "" && dim != "Z" && dim != "M" && dim != "ZM" {
+		return nil, fmt.Errorf("unexpected %q at offset %d", dim, p.pos)
+	}
+	if p.peek() != '(' && p.word() == "EMPTY" {
+		return nil, nil
+	}
+
+	polygons := [][][]Point{}
+	switch typ {
+	case "POLYGON":
+		polygon, err := p.polygon()
+		if err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, polygon)
+	case "MULTIPOLYGON":
+		err := p.list(func() error {
+			polygon, err := p.polygon()
+			polygons = append(polygons, polygon)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	case "GEOMETRYCOLLECTION":
+		err := p.list(func() error {
+			zult, err := p.geometry()
+			polygons = append(polygons, zult...)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	case "":
+		return nil, fmt.Errorf("expected geometry type at offset %d", p.pos)
+	default:
+		return nil, fmt.Errorf("unsupported WKT type %s, regions must be polygons", typ)
+	}
+	return polygons, nil
+}
+
+// parseWKTRegion returns the polygons from a WKT, or EWKT, POLYGON, MULTIPOLYGON, or
+// GEOMETRYCOLLECTION of polygons.
+func parseWKTRegion(s string) ([][][]Point, error) {
+	// EWKT SRID prefix
+	if strings.HasPrefix(strings.ToUpper(s), "SRID=") {
+		if idx := strings.Index(s, ";"); idx > 0 {
+			s = s[idx+1:]
+		}
+	}
+	p := &wktParser{s: s}
+	polygons, err := p.geometry()
+	if err != nil {
+		return nil, fmt.Errorf("invalid WKT: %w", err)
+	}
+	if p.peek() != 0 {
+		return nil, fmt.Errorf("invalid WKT: unexpected content at offset %d", p.pos)
+	}
+	return polygons, nil
+}
+
+// Shapefile shape types with polygon geometries
+var shapefilePolygonTypes = map[int32]bool{
+	5:  true, // Polygon
+	15: true, // PolygonZ
+	25: true, // PolygonM
+}
+
+// readShapefileRegion returns the polygons from the .shp file of an ESRI shapefile. Rings are
+// grouped into polygons using their orientation, where outer rings are clockwise, and
+// coordinates must be geographic, i.e., longitude and latitude.
+func readShapefileRegion(path string) ([][][]Point, error) {
+	prj, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".prj")
+	if err == nil && strings.Contains(strings.ToUpper(string(prj)), "PROJCS") {
+		return nil, fmt.Errorf("projected coordinate systems are not supported, reproject to WGS84")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 100)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, fmt.Errorf("reading shapefile header: %w", err)
+	}
+	if code := binary.BigEndian.Uint32(header[0:4]); code != 9994 {
+		return nil, fmt.Errorf("not a shapefile, invalid file code %d", code)
+	}
+	if typ := int32(binary.LittleEndian.Uint32(header[32:36])); !shapefilePolygonTypes[typ] {
+		return nil, fmt.Errorf("unsupported shape type %d, regions must be polygons", typ)
+	}
+
+	polygons := [][][]Point{}
+	recHeader := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(f, recHeader); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("reading shapefile record: %w", err)
+		}
+		// content length is in 16-bit words
+		content := make([]byte, 2*binary.BigEndian.Uint32(recHeader[4:8]))
+		if _, err := io.ReadFull(f, content); err != nil {
+			return nil, fmt.Errorf("reading shapefile record: %w", err)
+		}
+		zult, err := decodeShapefilePolygon(content)
+		if err != nil {
+			return nil, fmt.Errorf("shapefile record %d: %w", binary.BigEndian.Uint32(recHeader[0:4]), err)
+		}
+		polygons = append(polygons, zult...)
+	}
+	return polygons, nil
+}
+
+func decodeShapefilePolygon(content []byte) ([][][]Point, error) {
+	if len(content) < 4 {
+		return nil, fmt.Errorf("truncated record")
+	}
+	typ := int32(binary.LittleEndian.Uint32(content[0:4]))
+	if typ == 0 {
+		// null shape
+		return nil, nil
+	}
+	if !shapefilePolygonTypes[typ] {
+		return nil, fmt.Errorf("unsupported shape type %d", typ)
+	}
+	// type, bounding box, number of parts, number of points
+	if len(content) < 44 {
+		return nil, fmt.Errorf("truncated record")
+	}
+	numParts := int(binary.LittleEndian.Uint32(content[36:40]))
+	numPoints := int(binary.LittleEndian.Uint32(content[40:44]))
+	pointsStart := 44 + 4*numParts
+	if numParts < 1 || numPoints < 1 || len(content) < pointsStart+16*numPoints {
+		return nil, fmt.Errorf("truncated record")
+	}
+
+	rings := [][]Point{}
+	for i := 0; i < numParts; i++ {
+		start := int(binary.LittleEndian.Uint32(content[44+4*i:]))
+		end := numPoints
+		if i < numParts-1 {
+			end = int(binary.LittleEndian.Uint32(content[44+4*(i+1):]))
+		}
+		if start < 0 || end > numPoints || start >= end {
+			return nil, fmt.Errorf("invalid part index")
+		}
+		ring := []Point{}
+		for j := start; j < end; j++ {
+			off := pointsStart + 16*j
+			ring = append(ring, Point{
+				Lon: math.Float64frombits(binary.LittleEndian.Uint64(content[off:])),
+				Lat: math.Float64frombits(binary.LittleEndian.Uint64(content[off+8:])),
+			})
+		}
+		rings = append(rings, ring)
+	}
+
+	// Clockwise rings start a new polygon, counter-clockwise rings are holes in the previous
+	// polygon. Rings written in the wrong order are treated as polygons.
+	polygons := [][][]Point{}
+	for _, ring := range rings {
+		if signedArea(ring) < 0 || len(polygons) == 0 {
+			polygons = append(polygons, [][]Point{ring})
+			continue
+		}
+		last := len(polygons) - 1
+		polygons[last] = append(polygons[last], ring)
+	}
+	return polygons, nil
+}
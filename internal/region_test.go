@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// counter-clockwise unit square
+	ccwSquare = []Point{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	// clockwise square offset from ccwSquare
+	cwSquare = []Point{{10, 10}, {10, 11}, {11, 11}, {11, 10}, {10, 10}}
+)
+
+func writeRegionFile(t *testing.T, name string, dat []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, dat, 0o644))
+	return path
+}
+
+// writeShapefile writes a polygon shapefile with a record per polygon
+func writeShapefile(t *testing.T, polygons ...[][]Point) string {
+	body := &bytes.Buffer{}
+	le := func(v any) { require.NoError(t, binary.Write(body, binary.LittleEndian, v)) }
+	be := func(v any) { require.NoError(t, binary.Write(body, binary.BigEndian, v)) }
+	for i, rings := range polygons {
+		numPoints := 0
+		for _, ring := range rings {
+			numPoints += len(ring)
+		}
+		be(int32(i + 1))
+		be(int32((44 + 4*len(rings) + 16*numPoints) / 2))
+		le(int32(5))
+		le([4]float64{})
+		le(int32(len(rings)))
+		le(int32(numPoints))
+		idx := 0
+		for _, ring := range rings {
+			le(int32(idx))
+			idx += len(ring)
+		}
+		for _, ring := range rings {
+			for _, p := range ring {
+				le([2]float64{p.Lon, p.Lat})
+			}
+		}
+	}
+
+	header := make([]byte, 100)
+	binary.BigEndian.PutUint32(header[0:], 9994)
+	binary.BigEndian.PutUint32(header[24:], uint32((100+body.Len())/2))
+	binary.LittleEndian.PutUint32(header[28:], 1000)
+	binary.LittleEndian.PutUint32(header[32:], 5)
+	return writeRegionFile(t, "region.shp", append(header, body.Bytes()...))
+}
+
+func TestReadRegion(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Path     func(t *testing.T) string
+		Expected [][]Point
+	}{
+		{
+			"geojson polygon",
+			func(t *testing.T) string {
+				return writeRegionFile(t, "region.json",
+					[]byte(`{"type": "Polygon", "coordinates": [[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`))
+			},
+			[][]Point{ccwSquare},
+		},
+		{
+			"geojson feature collection",
+			func(t *testing.T) string {
+				return writeRegionFile(t, "region.geojson", []byte(`{
+					"type": "FeatureCollection",
+					"features": [
+						{"type": "Feature", "geometry": null, "properties": {}},
+						{"type": "Feature", "properties": {}, "geometry": {
+							"type": "MultiPolygon",
+							"coordinates": [
+								[[[0,0,5],[1,0,5],[1,1,5],[0,1,5],[0,0,5]]],
+								[[[10,10],[10,11],[11,11],[11,10],[10,10]], [[10.2,10.2],[10.4,10.2],[10.4,10.4],[10.2,10.2]]]
+							]
+						}}
+					]
+				}`))
+			},
+			[][]Point{ccwSquare, reversed(cwSquare)},
+		},
+		{
+			"wkt",
+			func(t *testing.T) string {
+				return writeRegionFile(t, "region.wkt", []byte("SRID=4326;POLYGON((0 0, 1 0, 1 1, 0 1, 0 0))\n"))
+			},
+			[][]Point{ccwSquare},
+		},
+		{
+			"wkt multipolygon z",
+			func(t *testing.T) string {
+				return writeRegionFile(t, "region.txt", []byte(
+					"multipolygon z (((0 0 1, 1 0 1, 1 1 1, 0 1 1)), ((10 10 0, 10 11 0, 11 11 0, 11 10 0, 10 10 0)))"))
+			},
+			[][]Point{ccwSquare, reversed(cwSquare)},
+		},
+		{
+			"wkt geometry collection",
+			func(t *testing.T) string {
+				return writeRegionFile(t, "region.wkt", []byte(
+					"GEOMETRYCOLLECTION (POLYGON EMPTY, POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0)))"))
+			},
+			[][]Point{ccwSquare},
+		},
+		{
+			"shapefile",
+			func(t *testing.T) string {
+				// outer rings are clockwise, holes counter-clockwise
+				hole := []Point{{10.2, 10.2}, {10.4, 10.2}, {10.4, 10.4}, {10.2, 10.2}}
+				return writeShapefile(t, [][]Point{cwSquare, hole}, [][]Point{reversed(ccwSquare)})
+			},
+			[][]Point{reversed(cwSquare), ccwSquare},
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.Name, func(t *testing.T) {
+			polygons, err := ReadRegion(test.Path(t), DefaultRegionMaxPoints)
+			require.NoError(t, err)
+			require.Equal(t, test.Expected, polygons)
+		})
+	}
+
+	errCases := []struct {
+		Name string
+		Path func(t *testing.T) string
+	}{
+		{"missing", func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing.json") }},
+		{"geojson point", func(t *testing.T) string {
+			return writeRegionFile(t, "region.json", []byte(`{"type": "Point", "coordinates": [1, 2]}`))
+		}},
+		{"geojson empty", func(t *testing.T) string {
+			return writeRegionFile(t, "region.json", []byte(`{"type": "FeatureCollection", "features": []}`))
+		}},
+		{"wkt linestring", func(t *testing.T) string {
+			return writeRegionFile(t, "region.wkt", []byte(`LINESTRING (0 0, 1 1)`))
+		}},
+		{"wkt trailing content", func(t *testing.T) string {
+			return writeRegionFile(t, "region.wkt", []byte(`POLYGON ((0 0, 1 0, 1 1, 0 0)) x`))
+		}},
+		{"wkt unclosed", func(t *testing.T) string {
+			return writeRegionFile(t, "region.wkt", []byte(`POLYGON ((0 0, 1 0, 1 1, 0 0)`))
+		}},
+		{"too few points", func(t *testing.T) string {
+			return writeRegionFile(t, "region.wkt", []byte(`POLYGON ((0 0, 1 0, 0 0))`))
+		}},
+		{"not a shapefile", func(t *testing.T) string {
+			return writeRegionFile(t, "region.shp", make([]byte, 100))
+		}},
+		{"projected shapefile", func(t *testing.T) string {
+			path := writeShapefile(t, [][]Point{cwSquare})
+			prj := filepath.Join(filepath.Dir(path), "region.prj")
+			require.NoError(t, os.WriteFile(prj, []byte(`PROJCS["WGS_1984_UTM_Zone_15N"]`), 0o644))
+			return path
+		}},
+	}
+	for _, test := range errCases {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := ReadRegion(test.Path(t), DefaultRegionMaxPoints)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNormalizeRing(t *testing.T) {
+	t.Run("closes and removes repeated points", func(t *testing.T) {
+		ring, err := normalizeRing([]Point{{0, 0}, {1, 0}, {1, 0}, {1, 1}, {0, 1}}, 0)
+		require.NoError(t, err)
+		require.Equal(t, ccwSquare, ring)
+	})
+
+	t.Run("no area", func(t *testing.T) {
+		_, err := normalizeRing([]Point{{0, 0}, {1, 0}, {2, 0}, {0, 0}}, 0)
+		require.Error(t, err)
+	})
+
+	t.Run("simplifies", func(t *testing.T) {
+		// clockwise circle with some noise
+		circle := []Point{}
+		for i := 0; i < 1000; i++ {
+			a := -2 * math.Pi * float64(i) / 1000
+			r := 10 + 0.001*float64(i%3)
+			circle = append(circle, Point{Lon: r * math.Cos(a), Lat: r * math.Sin(a)})
+		}
+
+		ring, err := normalizeRing(circle, 50)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(ring), 50)
+		require.GreaterOrEqual(t, len(ring), 4)
+		require.Equal(t, ring[0], ring[len(ring)-1], "ring is closed")
+		require.Greater(t, signedArea(ring), 0.0, "ring is counter-clockwise")
+		require.InDelta(t, math.Pi*100, signedArea(ring), 10, "simplified area is close to the original")
+	})
+}
@@ -0,0 +1,255 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmflynn/cmrfetch/internal/log"
+)
+
+// DefaultResponseCacheTTL is how long cached CMR search responses are used
+const DefaultResponseCacheTTL = time.Hour
+
+// cachedHeaders are the response headers stored with a cached response
+var cachedHeaders = []string{"cmr-hits", "cmr-search-after"}
+
+// ResponseCacheEntry is the metadata for a cached response
+type ResponseCacheEntry struct {
+	Key string `json:"key,omitempty"`
+	URL string `json:"url"`
+	// The cmr-search-after value of the request, if any
+	SearchAfter string      `json:"search_after,omitempty"`
+	Header      http.Header `json:"header"`
+	Created     time.Time   `json:"created"`
+	// When the entry expires according to the TTL of the cache that created it
+	Expires time.Time `json:"expires"`
+	// Size of the cached response body
+	Size int64 `json:"size"`
+}
+
+// ResponseCache is an on-disk cache of CMR responses keyed by the request url and
+// cmr-search-after value. Each entry is a file containing a line of JSON metadata followed
+// by the response body. A nil cache is valid and caches nothing.
+//
+// Expired entries are removed when they are read and, once per cache, when a response is
+// cached, so the cache does not grow without bound.
+type ResponseCache struct {
+	dir       string
+	ttl       time.Duration
+	refresh   bool
+	pruneOnce sync.Once
+}
+
+// DefaultResponseCacheDir is the location of the response cache in the user cache dir
+func DefaultResponseCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cmrfetch", "responses"), nil
+}
+
+// NewResponseCache creates a cache in dir where responses are used for ttl after they are
+// cached. The directory is created when the first response is cached.
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{dir: dir, ttl: ttl}
+}
+
+// WithRefresh sets whether to ignore existing entries, so every request is made and the
+// response replaces any cached response.
+func (c *ResponseCache) WithRefresh(refresh bool) *ResponseCache {
+	c.refresh = refresh
+	return c
+}
+
+// Dir is the cache directory
+func (c *ResponseCache) Dir() string {
+	return c.dir
+}
+
+// ResponseCacheKey returns the key for a request url and cmr-search-after value
+func ResponseCacheKey(url, searchAfter string) string {
+	sum := sha256.Sum256([]byte(url + "\n" + searchAfter))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key)
+}
+
+// Get returns the cached response header and body for a request, if there is one that has
+// not expired. Unreadable entries are treated as missing.
+func (c *ResponseCache) Get(url, searchAfter string) (http.Header, []byte, bool) {
+	if c == nil || c.refresh {
+		return nil, nil, false
+	}
+	key := ResponseCacheKey(url, searchAfter)
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Debug("opening cache entry %s: %s", key, err)
+		}
+		return nil, nil, false
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	entry, err := readCacheEntry(r)
+	if err != nil {
+		log.Debug("reading cache entry %s: %s", key, err)
+		return nil, nil, false
+	}
+	// guard against hash collisions, however unlikely
+	if entry.URL != url || entry.SearchAfter != searchAfter {
+		return nil, nil, false
+	}
+	if time.Since(entry.Created) >= c.ttl || time.Now().After(entry.Expires) {
+		log.Debug("cache entry %s expired", key)
+		f.Close()
+		if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Debug("removing expired cache entry %s: %s", key, err)
+		}
+		return nil, nil, false
+	}
+	body, err := io.ReadAll(r)
+	if err != nil || int64(len(body)) != entry.Size {
+		log.Debug("reading cache entry %s body: size=%d err=%v", key, len(body), err)
+		return nil, nil, false
+	}
+	log.Debug("using cached response %s for %s", key, url)
+	return entry.Header, body, true
+}
+
+// Put caches a response for a request. Only the CMR headers needed to page through results
+// are cached.
+func (c *ResponseCache) Put(url, searchAfter string, header http.Header, body []byte) error {
+	if c == nil {
+		return nil
+	}
+	c.pruneOnce.Do(c.prune)
+	now := time.Now().UTC()
+	entry := ResponseCacheEntry{
+		URL:         url,
+		SearchAfter: searchAfter,
+		Header:      http.Header{},
+		Created:     now,
+		Expires:     now.Add(c.ttl),
+		Size:        int64(len(body)),
+	}
+	for _, name := range cachedHeaders {
+		if v := header.Get(name); v != "" {
+			entry.Header.Set(name, v)
+		}
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, len(meta)+1+len(body)))
+	buf.Write(meta)
+	buf.WriteByte('\n')
+	buf.Write(body)
+	return WriteFileAtomic(c.path(ResponseCacheKey(url, searchAfter)), buf.Bytes(), 0o644)
+}
+
+func readCacheEntry(r *bufio.Reader) (ResponseCacheEntry, error) {
+	entry := ResponseCacheEntry{}
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return entry, fmt.Errorf("reading metadata: %w", err)
+	}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return entry, fmt.Errorf("decoding metadata: %w", err)
+	}
+	return entry, nil
+}
+
+// Entries returns the metadata for all cache entries, oldest first. Entries that cannot be
+// read are skipped.
+func (c *ResponseCache) Entries() ([]ResponseCacheEntry, error) {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	entries := []ResponseCacheEntry{}
+	for _, file := range files {
+		// skip temp files from in-progress writes
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		entry, err := c.readEntry(file.Name())
+		if err != nil {
+			log.Debug("reading cache entry %s: %s", file.Name(), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Created.Before(entries[j].Created) })
+	return entries, nil
+}
+
+func (c *ResponseCache) readEntry(key string) (ResponseCacheEntry, error) {
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		return ResponseCacheEntry{}, err
+	}
+	defer f.Close()
+	entry, err := readCacheEntry(bufio.NewReader(f))
+	entry.Key = key
+	return entry, err
+}
+
+// prune removes expired entries
+func (c *ResponseCache) prune() {
+	now := time.Now()
+	count, err := c.Purge(func(entry ResponseCacheEntry) bool { return now.After(entry.Expires) })
+	if err != nil {
+		log.Debug("pruning cache: %s", err)
+	}
+	log.Debug("pruned %d expired cache entries", count)
+}
+
+// Purge removes the entries for which remove returns true, or all entries if remove is nil.
+// Entries that cannot be read are always removed. It returns the number of entries removed.
+func (c *ResponseCache) Purge(remove func(ResponseCacheEntry) bool) (int, error) {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	count := 0
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		if remove != nil {
+			entry, err := c.readEntry(file.Name())
+			if err == nil && !remove(entry) {
+				continue
+			}
+		}
+		if err := os.Remove(c.path(file.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	header := http.Header{}
+	header.Set("cmr-hits", "2")
+	header.Set("cmr-search-after", "page2")
+	header.Set("cmr-request-id", "xxx")
+
+	t.Run("put and get", func(t *testing.T) {
+		cache := NewResponseCache(t.TempDir(), time.Hour)
+		require.NoError(t, cache.Put("http://x/granules", "", header, []byte(`{"items": [1]}`)))
+
+		got, body, ok := cache.Get("http://x/granules", "")
+		require.True(t, ok)
+		require.Equal(t, `{"items": [1]}`, string(body))
+		require.Equal(t, "2", got.Get("cmr-hits"))
+		require.Equal(t, "page2", got.Get("cmr-search-after"))
+		require.Empty(t, got.Get("cmr-request-id"), "only paging headers should be cached")
+
+		_, _, ok = cache.Get("http://x/granules", "page2")
+		require.False(t, ok, "search-after should be part of the key")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, NewResponseCache(dir, time.Hour).Put("http://x", "", header, nil))
+
+		_, _, ok := NewResponseCache(dir, time.Nanosecond).Get("http://x", "")
+		require.False(t, ok)
+		require.NoFileExists(t, filepath.Join(dir, ResponseCacheKey("http://x", "")),
+			"expired entries should be removed")
+	})
+
+	t.Run("put prunes expired entries", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, NewResponseCache(dir, time.Nanosecond).Put("http://x/stale", "", header, nil))
+		require.NoError(t, NewResponseCache(dir, 30*24*time.Hour).Put("http://x/holdings", "", header, nil))
+		time.Sleep(time.Millisecond)
+
+		cache := NewResponseCache(dir, time.Hour)
+		require.NoError(t, cache.Put("http://x/new", "", header, nil))
+
+		entries, err := cache.Entries()
+		require.NoError(t, err)
+		urls := []string{}
+		for _, entry := range entries {
+			urls = append(urls, entry.URL)
+		}
+		require.ElementsMatch(t, []string{"http://x/holdings", "http://x/new"}, urls,
+			"entries should expire according to the TTL they were cached with")
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		cache := NewResponseCache(t.TempDir(), time.Hour)
+		require.NoError(t, cache.Put("http://x", "", header, nil))
+
+		_, _, ok := cache.WithRefresh(true).Get("http://x", "")
+		require.False(t, ok)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var cache *ResponseCache
+		require.NoError(t, cache.Put("http://x", "", header, nil))
+		_, _, ok := cache.Get("http://x", "")
+		require.False(t, ok)
+	})
+
+	t.Run("corrupt entry is a miss", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := NewResponseCache(dir, time.Hour)
+		require.NoError(t, cache.Put("http://x", "", header, []byte("body")))
+		fpath := filepath.Join(dir, ResponseCacheKey("http://x", ""))
+		dat, err := os.ReadFile(fpath)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(fpath, dat[:len(dat)-1], 0o644))
+
+		_, _, ok := cache.Get("http://x", "")
+		require.False(t, ok, "truncated body should not be used")
+	})
+
+	t.Run("entries and purge", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := NewResponseCache(dir, time.Hour)
+
+		entries, err := NewResponseCache(filepath.Join(dir, "missing"), time.Hour).Entries()
+		require.NoError(t, err)
+		require.Empty(t, entries)
+
+		require.NoError(t, cache.Put("http://x/collections", "", header, []byte("a")))
+		require.NoError(t, cache.Put("http://x/granules", "", header, []byte("bb")))
+		require.NoError(t, cache.Put("http://x/granules", "page2", header, []byte("ccc")))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "garbage"), []byte("x"), 0o644))
+
+		entries, err = cache.Entries()
+		require.NoError(t, err)
+		require.Len(t, entries, 3, "unreadable entries should be skipped")
+		require.Equal(t, "http://x/collections", entries[0].URL)
+		require.Equal(t, int64(1), entries[0].Size)
+		require.Equal(t, ResponseCacheKey("http://x/collections", ""), entries[0].Key)
+
+		count, err := cache.Purge(func(e ResponseCacheEntry) bool { return e.URL == "http://x/granules" })
+		require.NoError(t, err)
+		require.Equal(t, 3, count, "expected matching and unreadable entries to be removed")
+		entries, err = cache.Entries()
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+
+		count, err = cache.Purge(nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+	})
+}
+
+func TestCMRSearchAPIWithCache(t *testing.T) {
+	requests := 0
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("cmr-hits", "2")
+		if r.Header.Get("cmr-search-after") == "" {
+			w.Header().Set("cmr-search-after", "page2")
+			_, _ = w.Write([]byte(`{"items": [1]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items": [2]}`))
+	}))
+	defer svr.Close()
+
+	get := func(t *testing.T, cache *ResponseCache) []int64 {
+		t.Helper()
+		zult, err := NewCMRSearchAPI().WithCache(cache).Get(context.Background(), svr.URL)
+		require.NoError(t, err)
+		results := []int64{}
+		for r := range zult.Ch {
+			results = append(results, r.Int())
+		}
+		require.NoError(t, zult.Err())
+		return results
+	}
+
+	dir := t.TempDir()
+	cache := NewResponseCache(dir, time.Hour)
+
+	require.Equal(t, []int64{1, 2}, get(t, cache))
+	require.Equal(t, 2, requests)
+
+	require.Equal(t, []int64{1, 2}, get(t, cache), "cached pages should include search-after")
+	require.Equal(t, 2, requests, "expected all pages from the cache")
+
+	require.Equal(t, []int64{1, 2}, get(t, NewResponseCache(dir, time.Hour).WithRefresh(true)))
+	require.Equal(t, 4, requests, "refresh should not use cached pages")
+}
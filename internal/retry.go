@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how many times, and how often, a failed operation is attempted.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values less than 1 are
+	// treated as 1, i.e., no retries.
+	MaxAttempts int
+	// InitialBackoff is the base delay before the first retry. The delay doubles for every
+	// subsequent retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed delay between attempts.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the policy used when one is not otherwise configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Backoff returns how long to wait before the attempt following attempt, where attempt
+// starts at 1. The delay grows exponentially from InitialBackoff with full jitter, unless
+// err provides a Retry-After value, in which case that is used instead. Either way the delay
+// is capped at MaxBackoff.
+func (p RetryPolicy) Backoff(attempt int, err error) time.Duration {
+	if after := retryAfter(err); after > 0 {
+		if p.MaxBackoff > 0 && after > p.MaxBackoff {
+			return p.MaxBackoff
+		}
+		return after
+	}
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+	delay := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// Do calls fn until it succeeds, returns an error that is not retryable, or the maximum
+// number of attempts is reached. onRetry, if not nil, is called before waiting for the next
+// attempt. The number of attempts made is returned along with the last error.
+func (p RetryPolicy) Do(
+	ctx context.Context,
+	fn func() error,
+	onRetry func(attempt int, delay time.Duration, err error),
+) (int, error) {
+	var attempt int
+	for {
+		attempt++
+		err := fn()
+		if err == nil || attempt >= p.attempts() || !IsRetryable(err) || ctx.Err() != nil {
+			return attempt, err
+		}
+		delay := p.Backoff(attempt, err)
+		if onRetry != nil {
+			onRetry(attempt, delay, err)
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryAfterError is implemented by errors that carry a server provided Retry-After delay.
+type retryAfterError interface {
+	RetryAfter() time.Duration
+}
+
+func retryAfter(err error) time.Duration {
+	var raErr retryAfterError
+	if errors.As(err, &raErr) {
+		return raErr.RetryAfter()
+	}
+	return 0
+}
+
+// statusCodeError is implemented by errors resulting from an HTTP response status.
+type statusCodeError interface {
+	HTTPStatusCode() int
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+}
+
+// IsRetryable returns true if err is likely transient, such as a network timeout, reset or
+// refused connection, or an HTTP 429 or 5xx response status. Any other error, including
+// those from canceled contexts, is considered permanent.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var scErr statusCodeError
+	if errors.As(err, &scErr) {
+		return retryableStatus(scErr.HTTPStatusCode())
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	// Other network errors, e.g., invalid certificates or unsupported protocols, are
+	// permanent, but *url.Error implements net.Error so only timeouts are considered.
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter parses a Retry-After header value given as either delay seconds or an
+// HTTP date. Zero is returned if the value is empty or invalid.
+func parseRetryAfter(val string) time.Duration {
+	if val == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
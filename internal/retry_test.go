@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Err      error
+		Expected bool
+	}{
+		{"nil", nil, false},
+		{"503", &FailedDownload{StatusCode: http.StatusServiceUnavailable}, true},
+		{"429", &FailedDownload{StatusCode: http.StatusTooManyRequests}, true},
+		{"404", &FailedDownload{StatusCode: http.StatusNotFound}, false},
+		{"wrapped 500", fmt.Errorf("x: %w", &FailedDownload{StatusCode: 500}), true},
+		{"unexpected eof", fmt.Errorf("reading: %w", io.ErrUnexpectedEOF), true},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"timeout", &url.Error{Op: "Get", URL: "https://x", Err: &net.DNSError{IsTimeout: true}}, true},
+		{"net error", &net.OpError{Op: "dial", Err: fmt.Errorf("unsupported")}, false},
+		{"x509", &url.Error{Op: "Get", URL: "https://x", Err: x509.UnknownAuthorityError{}}, false},
+		{"canceled", context.Canceled, false},
+		{"other", fmt.Errorf("got checksum x, expected y"), false},
+	}
+
+	for _, test := range cases {
+		t.Run(test.Name, func(t *testing.T) {
+			require.Equal(t, test.Expected, IsRetryable(test.Err))
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	require.Equal(t, time.Duration(0), parseRetryAfter(""))
+	require.Equal(t, time.Duration(0), parseRetryAfter("xxx"))
+	require.Equal(t, 5*time.Second, parseRetryAfter("5"))
+
+	d := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.Greater(t, d, 59*time.Minute)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 4 * time.Second}
+
+	for attempt := 1; attempt < 10; attempt++ {
+		d := policy.Backoff(attempt, nil)
+		require.GreaterOrEqual(t, d, time.Duration(0))
+		require.LessOrEqual(t, d, 4*time.Second)
+	}
+
+	err := &FailedDownload{StatusCode: 503, retryAfter: 3 * time.Second}
+	require.Equal(t, 3*time.Second, policy.Backoff(1, err), "Retry-After should take priority")
+
+	err = &FailedDownload{StatusCode: 503, retryAfter: time.Hour}
+	require.Equal(t, 4*time.Second, policy.Backoff(1, err), "Retry-After should be capped")
+	require.Equal(t, time.Hour, RetryPolicy{}.Backoff(1, err), "Retry-After should not be capped without a max")
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	t.Run("retryable", func(t *testing.T) {
+		calls := 0
+		attempts, err := policy.Do(context.Background(), func() error {
+			calls++
+			if calls < 3 {
+				return &FailedDownload{StatusCode: 503}
+			}
+			return nil
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		retries := 0
+		attempts, err := policy.Do(context.Background(), func() error {
+			return &FailedDownload{StatusCode: 503}
+		}, func(int, time.Duration, error) { retries++ })
+		require.Error(t, err)
+		require.Equal(t, 3, attempts)
+		require.Equal(t, 2, retries)
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		attempts, err := policy.Do(context.Background(), func() error {
+			return &FailedDownload{StatusCode: 404}
+		}, nil)
+		require.Error(t, err)
+		require.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// bsdSum implements the 16-bit BSD checksum, as computed by `sum -r`.
+type bsdSum struct {
+	sum uint16
+}
+
+var _ hash.Hash = (*bsdSum)(nil)
+
+func (s *bsdSum) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.sum = (s.sum >> 1) | (s.sum << 15)
+		s.sum += uint16(b)
+	}
+	return len(p), nil
+}
+
+func (s *bsdSum) Sum(b []byte) []byte { return binary.BigEndian.AppendUint16(b, s.sum) }
+
+func (s *bsdSum) Reset() { s.sum = 0 }
+
+func (s *bsdSum) Size() int { return 2 }
+
+func (s *bsdSum) BlockSize() int { return 1 }
+
+// sysvSum implements the 16-bit System V checksum, as computed by `sum -s`.
+type sysvSum struct {
+	sum uint32
+}
+
+var _ hash.Hash = (*sysvSum)(nil)
+
+func (s *sysvSum) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.sum += uint32(b)
+	}
+	return len(p), nil
+}
+
+func (s *sysvSum) Sum(b []byte) []byte {
+	r := (s.sum & 0xffff) + (s.sum >> 16)
+	return binary.BigEndian.AppendUint16(b, uint16((r&0xffff)+(r>>16)))
+}
+
+func (s *sysvSum) Reset() { s.sum = 0 }
+
+func (s *sysvSum) Size() int { return 2 }
+
+func (s *sysvSum) BlockSize() int { return 1 }
+
+// posixCksumTable is the MSB-first CRC table for the CRC-32 polynomial used by cksum
+var posixCksumTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// posixCksum implements the POSIX cksum CRC, which is a non-reflected CRC-32 of the data
+// followed by its length, as computed by `cksum`.
+type posixCksum struct {
+	crc    uint32
+	length uint64
+}
+
+var _ hash.Hash = (*posixCksum)(nil)
+
+func (c *posixCksum) update(p []byte) {
+	for _, b := range p {
+		c.crc = c.crc<<8 ^ posixCksumTable[byte(c.crc>>24)^b]
+	}
+}
+
+func (c *posixCksum) Write(p []byte) (int, error) {
+	c.update(p)
+	c.length += uint64(len(p))
+	return len(p), nil
+}
+
+func (c *posixCksum) Sum(b []byte) []byte {
+	final := *c
+	// the length is appended least significant byte first, using as few bytes as needed
+	for n := c.length; n > 0; n >>= 8 {
+		final.update([]byte{byte(n)})
+	}
+	return binary.BigEndian.AppendUint32(b, ^final.crc)
+}
+
+func (c *posixCksum) Reset() { c.crc, c.length = 0, 0 }
+
+func (c *posixCksum) Size() int { return 4 }
+
+func (c *posixCksum) BlockSize() int { return 1 }
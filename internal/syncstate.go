@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SyncQueryState is the sync progress for a single granule query.
+type SyncQueryState struct {
+	// Encoded query, for reference only
+	Query string `json:"query"`
+	// Revision date of the newest granule processed. The next poll searches for granules
+	// updated since the watermark.
+	Watermark time.Time `json:"watermark"`
+	// Granule files, as <concept-id>:<revision-id>/<name>, with a revision date on or after the
+	// watermark that have already been processed. Because updated_since is inclusive these will
+	// be returned again by the next poll.
+	Seen []string `json:"seen"`
+	// When the state was last updated
+	Updated time.Time `json:"updated"`
+}
+
+// SyncState is the persisted state of all sync queries, keyed by SyncQueryKey.
+type SyncState struct {
+	path    string
+	Queries map[string]SyncQueryState `json:"queries"`
+}
+
+// SyncQueryKey returns the key identifying a query against a CMR search url
+func SyncQueryKey(searchURL, query string) string {
+	sum := sha256.Sum256([]byte(searchURL + "?" + query))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadSyncState loads state from path. If path does not exist an empty state is returned.
+func LoadSyncState(path string) (*SyncState, error) {
+	state := &SyncState{path: path, Queries: map[string]SyncQueryState{}}
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("reading sync state: %w", err)
+	}
+	if err := json.Unmarshal(dat, state); err != nil {
+		return nil, fmt.Errorf("decoding sync state %s: %w", path, err)
+	}
+	if state.Queries == nil {
+		state.Queries = map[string]SyncQueryState{}
+	}
+	return state, nil
+}
+
+// Get returns the state for key and whether it exists
+func (s *SyncState) Get(key string) (SyncQueryState, bool) {
+	q, ok := s.Queries[key]
+	return q, ok
+}
+
+// Set updates the state for key and saves the state to disk
+func (s *SyncState) Set(key string, q SyncQueryState) error {
+	q.Updated = time.Now().UTC()
+	s.Queries[key] = q
+	dat, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.path, dat, 0o644)
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncState(t *testing.T) {
+	t.Run("missing file is empty", func(t *testing.T) {
+		state, err := LoadSyncState(filepath.Join(t.TempDir(), "state.json"))
+		require.NoError(t, err)
+		require.Empty(t, state.Queries)
+	})
+
+	t.Run("persists", func(t *testing.T) {
+		fpath := filepath.Join(t.TempDir(), "sync", "state.json")
+		state, err := LoadSyncState(fpath)
+		require.NoError(t, err)
+
+		key := SyncQueryKey("https://cmr.earthdata.nasa.gov/search", "collection_concept_id=C1")
+		watermark := time.Date(2023, 4, 27, 19, 29, 7, 0, time.UTC)
+		require.NoError(t, state.Set(key, SyncQueryState{
+			Query:     "collection_concept_id=C1",
+			Watermark: watermark,
+			Seen:      []string{"G1-P:1"},
+		}))
+
+		state, err = LoadSyncState(fpath)
+		require.NoError(t, err)
+		q, ok := state.Get(key)
+		require.True(t, ok)
+		require.True(t, q.Watermark.Equal(watermark))
+		require.Equal(t, []string{"G1-P:1"}, q.Seen)
+		require.False(t, q.Updated.IsZero())
+
+		_, ok = state.Get(SyncQueryKey("https://cmr.earthdata.nasa.gov/search", "collection_concept_id=C2"))
+		require.False(t, ok)
+	})
+
+	t.Run("invalid file is err", func(t *testing.T) {
+		fpath := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, os.WriteFile(fpath, []byte("xxx"), 0o644))
+		_, err := LoadSyncState(fpath)
+		require.Error(t, err)
+	})
+}
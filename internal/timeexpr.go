@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeExprHelp describes the time expressions accepted by ParseTimeExpr
+const TimeExprHelp = "<yyyy-mm-dd>[T<hh:mm:ss>Z], <yyyy>[-]<doy>, now, today, yesterday, " +
+	"or a duration relative to one of those, e.g., -6h or now-2d"
+
+// timeNow is the current time used for relative expressions, replaced by tests
+var timeNow = time.Now
+
+var timeExprLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+}
+
+var (
+	doyExpr      = regexp.MustCompile(`^(\d{4})-?(\d{3})$`)
+	relativeExpr = regexp.MustCompile(`^(now|today|yesterday)?(?:([+-])(\d+)([smhdw]))?$`)
+)
+
+var relativeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseTimeExpr parses an absolute, day-of-year, or relative time expression; see TimeExprHelp.
+// Relative expressions are relative to now, where today and yesterday are the start of the
+// UTC day. All times are UTC.
+func ParseTimeExpr(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeExprLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	if m := doyExpr.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		doy, _ := strconv.Atoi(m[2])
+		jan1 := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		if doy < 1 || doy > jan1.AddDate(1, 0, -1).YearDay() {
+			return time.Time{}, fmt.Errorf("invalid day of year %d for %d", doy, year)
+		}
+		return jan1.AddDate(0, 0, doy-1), nil
+	}
+
+	if m := relativeExpr.FindStringSubmatch(strings.ToLower(s)); m != nil && s != "" {
+		now = now.UTC()
+		t := now
+		switch m[1] {
+		case "today":
+			t = now.Truncate(24 * time.Hour)
+		case "yesterday":
+			t = now.Truncate(24*time.Hour).AddDate(0, 0, -1)
+		}
+		if m[2] != "" {
+			n, err := strconv.Atoi(m[3])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+			}
+			offset := time.Duration(n) * relativeUnits[m[4]]
+			if m[2] == "-" {
+				offset = -offset
+			}
+			t = t.Add(offset)
+		}
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q; expected %s", s, TimeExprHelp)
+}
+
+// Season is an inclusive day of year window. StartDay may be greater than EndDay for a window
+// that spans the end of the year.
+type Season struct {
+	StartDay int
+	EndDay   int
+}
+
+// seasonDay parses a day of year, or <mm>-<dd> which is converted using a non-leap year
+func seasonDay(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse("01-02", s); err == nil {
+		return time.Date(2001, t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).YearDay(), nil
+	}
+	day, err := strconv.Atoi(s)
+	if err != nil || day < 1 || day > 366 {
+		return 0, fmt.Errorf("invalid season day %q; expected a day of year 1-366 or <mm>-<dd>", s)
+	}
+	return day, nil
+}
+
+// ParseSeason parses a season as <start>,<end> where each is a day of year or <mm>-<dd>,
+// e.g., 182,212 or 07-01,07-31.
+func ParseSeason(s string) (Season, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return Season{}, fmt.Errorf("expected season as <start>,<end>, e.g., 182,212 or 07-01,07-31")
+	}
+	start, err := seasonDay(parts[0])
+	if err != nil {
+		return Season{}, err
+	}
+	end, err := seasonDay(parts[1])
+	if err != nil {
+		return Season{}, err
+	}
+	return Season{StartDay: start, EndDay: end}, nil
+}
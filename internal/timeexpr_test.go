@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeExpr(t *testing.T) {
+	now := time.Date(2023, 4, 25, 13, 14, 15, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2023-04-25T01:02:03Z":      time.Date(2023, 4, 25, 1, 2, 3, 0, time.UTC),
+		"2023-04-25T01:02:03-01:00": time.Date(2023, 4, 25, 2, 2, 3, 0, time.UTC),
+		"2023-04-25":                time.Date(2023, 4, 25, 0, 0, 0, 0, time.UTC),
+		"2023115":                   time.Date(2023, 4, 25, 0, 0, 0, 0, time.UTC),
+		"2023-115":                  time.Date(2023, 4, 25, 0, 0, 0, 0, time.UTC),
+		"2020366":                   time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC),
+		"now":                       now,
+		"NOW":                       now,
+		" today ":                   time.Date(2023, 4, 25, 0, 0, 0, 0, time.UTC),
+		"yesterday":                 time.Date(2023, 4, 24, 0, 0, 0, 0, time.UTC),
+		"-6h":                       now.Add(-6 * time.Hour),
+		"+30m":                      now.Add(30 * time.Minute),
+		"now-2d":                    now.AddDate(0, 0, -2),
+		"today-1w":                  time.Date(2023, 4, 18, 0, 0, 0, 0, time.UTC),
+		"yesterday+12h":             time.Date(2023, 4, 24, 12, 0, 0, 0, time.UTC),
+		"-90s":                      now.Add(-90 * time.Second),
+	}
+	for expr, expected := range cases {
+		t.Run(expr, func(t *testing.T) {
+			zult, err := ParseTimeExpr(expr, now)
+			require.NoError(t, err)
+			require.True(t, expected.Equal(zult), "expected %s, got %s", expected, zult)
+		})
+	}
+
+	for _, expr := range []string{"", "a", "2023000", "2023366", "20231150", "-6", "6h", "now-", "now-2y", "2023-13-01"} {
+		t.Run("invalid "+expr, func(t *testing.T) {
+			_, err := ParseTimeExpr(expr, now)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseSeason(t *testing.T) {
+	cases := map[string]Season{
+		"182,212":     {182, 212},
+		"07-01,07-31": {182, 212},
+		"12-01, 59":   {335, 59},
+		"366,1":       {366, 1},
+	}
+	for s, expected := range cases {
+		t.Run(s, func(t *testing.T) {
+			season, err := ParseSeason(s)
+			require.NoError(t, err)
+			require.Equal(t, expected, season)
+		})
+	}
+
+	for _, s := range []string{"", "182", "0,10", "1,367", "13-01,12-01", "1,2,3"} {
+		t.Run("invalid "+s, func(t *testing.T) {
+			_, err := ParseSeason(s)
+			require.Error(t, err)
+		})
+	}
+}
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,15 +13,22 @@ type TimeRange struct {
 }
 
 type CMRError struct {
-	RequestID string
-	Status    string
-	Err       error
+	RequestID  string
+	Status     string
+	StatusCode int
+	Err        error
+	// Server requested delay before retrying, if any
+	retryAfter time.Duration
 }
 
 func (e *CMRError) Error() string {
 	return fmt.Sprintf("%s; error=%s; request-id=%s", e.Status, e.Err, e.RequestID)
 }
 
+func (e *CMRError) HTTPStatusCode() int { return e.StatusCode }
+
+func (e *CMRError) RetryAfter() time.Duration { return e.retryAfter }
+
 func encodeTime(t time.Time) string {
 	return t.Format("2006-01-02T15:04:05Z")
 }
@@ -31,3 +40,83 @@ func encodeTimeRange(tr TimeRange) string {
 	}
 	return s
 }
+
+// FloatRange is a numeric range where either, but not both, of Min and Max may be nil for an
+// open range. A range where Min equals Max is a single value.
+type FloatRange struct {
+	Min *float64
+	Max *float64
+}
+
+// ParseFloatRange parses a single value, or a range as <min>,<max> where either value may be
+// omitted for an open range, e.g., ",20".
+func ParseFloatRange(s string) (FloatRange, error) {
+	r, err := parseFloatRange(s)
+	if err != nil {
+		return r, err
+	}
+	if r.Min != nil && r.Max != nil && *r.Max < *r.Min {
+		return FloatRange{}, fmt.Errorf("max %v is less than min %v", *r.Max, *r.Min)
+	}
+	return r, nil
+}
+
+// ParseLongitudeRange parses a longitude range as <min>,<max>, where min may be greater than
+// max for a range crossing the antimeridian, e.g., 170,-170. Both values are required and must
+// be within -180 to 180.
+func ParseLongitudeRange(s string) (FloatRange, error) {
+	r, err := parseFloatRange(s)
+	if err != nil {
+		return r, err
+	}
+	if r.Min == nil || r.Max == nil {
+		return FloatRange{}, fmt.Errorf("expected <min>,<max>, got %q", s)
+	}
+	for _, v := range []float64{*r.Min, *r.Max} {
+		if v < -180 || v > 180 {
+			return FloatRange{}, fmt.Errorf("longitude %v is not within -180 to 180", v)
+		}
+	}
+	return r, nil
+}
+
+func parseFloatRange(s string) (FloatRange, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) > 2 {
+		return FloatRange{}, fmt.Errorf("expected <value> or <min>,<max>, got %q", s)
+	}
+	vals := []*float64{}
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			vals = append(vals, nil)
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return FloatRange{}, fmt.Errorf("invalid number %q", part)
+		}
+		vals = append(vals, &v)
+	}
+	r := FloatRange{Min: vals[0], Max: vals[len(vals)-1]}
+	if r.Min == nil && r.Max == nil {
+		return FloatRange{}, fmt.Errorf("at least one of min or max is required")
+	}
+	return r, nil
+}
+
+// String encodes the range as a CMR search param value
+func (r FloatRange) String() string {
+	if r.Min != nil && r.Max != nil && *r.Min == *r.Max {
+		return fmt.Sprintf("%v", *r.Min)
+	}
+	s := ""
+	if r.Min != nil {
+		s += fmt.Sprintf("%v", *r.Min)
+	}
+	s += ","
+	if r.Max != nil {
+		s += fmt.Sprintf("%v", *r.Max)
+	}
+	return s
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFloatRange(t *testing.T) {
+	cases := map[string]string{
+		"5":       "5",
+		"1.5,2.5": "1.5,2.5",
+		",20":     ",20",
+		"10,":     "10,",
+		" 3 , 3 ": "3",
+	}
+	for s, expected := range cases {
+		t.Run(s, func(t *testing.T) {
+			r, err := ParseFloatRange(s)
+			require.NoError(t, err)
+			require.Equal(t, expected, r.String())
+		})
+	}
+
+	for _, s := range []string{"", ",", "a", "1,a", "2,1", "1,2,3"} {
+		t.Run("invalid "+s, func(t *testing.T) {
+			_, err := ParseFloatRange(s)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseLongitudeRange(t *testing.T) {
+	cases := map[string]string{
+		"-10,10":    "-10,10",
+		"170,-170":  "170,-170",
+		"-180,180":  "-180,180",
+		" 90 , 90 ": "90",
+		"90":        "90",
+	}
+	for s, expected := range cases {
+		t.Run(s, func(t *testing.T) {
+			r, err := ParseLongitudeRange(s)
+			require.NoError(t, err)
+			require.Equal(t, expected, r.String())
+		})
+	}
+
+	for _, s := range []string{"", ",10", "10,", "-181,0", "0,180.5", "a,1"} {
+		t.Run("invalid "+s, func(t *testing.T) {
+			_, err := ParseLongitudeRange(s)
+			require.Error(t, err)
+		})
+	}
+}